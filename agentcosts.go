@@ -0,0 +1,65 @@
+package mix
+
+import (
+	"context"
+)
+
+// AgentCost is the usage the server reports for the session of one agent.
+type AgentCost struct {
+	SessionID string
+	// ID of the Task tool call that spawned the agent; empty for the root
+	// agent
+	ToolCallID       string
+	Cost             float64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// AgentCosts returns the usage of a session's root agent and of every
+// subagent it spawned, directly or through other subagents, keyed by the ID
+// of the spawning Task tool call, which is events.Agent.ID, and by "" for the
+// root agent. Stream events carry no usage, so it is read from ListSessions;
+// figures for a running agent are those of its last completed turn.
+func (s *Sessions) AgentCosts(ctx context.Context, sessionID string) (map[string]AgentCost, error) {
+	res, err := s.ListSessions(ctx, Bool(true))
+	if err != nil {
+		return nil, err
+	}
+
+	costs := map[string]AgentCost{}
+	children := map[string][]int{}
+	for i, session := range res.SessionData {
+		switch {
+		case session.ID == sessionID:
+			costs[""] = AgentCost{
+				SessionID:        session.ID,
+				Cost:             session.Cost,
+				PromptTokens:     session.PromptTokens,
+				CompletionTokens: session.CompletionTokens,
+			}
+		case session.ParentSessionID != nil && session.ParentToolCallID != nil:
+			children[*session.ParentSessionID] = append(children[*session.ParentSessionID], i)
+		}
+	}
+
+	pending := []string{sessionID}
+	for len(pending) > 0 {
+		parent := pending[0]
+		pending = pending[1:]
+		for _, i := range children[parent] {
+			session := res.SessionData[i]
+			if _, seen := costs[*session.ParentToolCallID]; seen {
+				continue
+			}
+			costs[*session.ParentToolCallID] = AgentCost{
+				SessionID:        session.ID,
+				ToolCallID:       *session.ParentToolCallID,
+				Cost:             session.Cost,
+				PromptTokens:     session.PromptTokens,
+				CompletionTokens: session.CompletionTokens,
+			}
+			pending = append(pending, session.ID)
+		}
+	}
+	return costs, nil
+}
@@ -0,0 +1,41 @@
+package mix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_AgentCosts(t *testing.T) {
+	t.Parallel()
+	session := func(id, parent, toolCallID string, cost float64) string {
+		s := map[string]any{"id": id, "title": id, "createdAt": "2026-01-01T00:00:00Z", "cost": cost, "promptTokens": 10}
+		if parent != "" {
+			s["parentSessionId"], s["parentToolCallId"] = parent, toolCallID
+		}
+		data, err := json.Marshal(s)
+		require.NoError(t, err)
+		return string(data)
+	}
+	ts := httptest.NewServer(&sessionServer{listed: []string{
+		session("s1", "", "", 1),
+		session("sub1", "s1", "task1", 0.25),
+		session("sub2", "sub1", "task2", 0.5),
+		session("other", "", "", 8),
+		session("sub3", "other", "task3", 4),
+	}})
+	defer ts.Close()
+	client := New(ts.URL)
+
+	costs, err := client.Sessions.AgentCosts(context.Background(), "s1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]AgentCost{
+		"":      {SessionID: "s1", Cost: 1, PromptTokens: 10},
+		"task1": {SessionID: "sub1", ToolCallID: "task1", Cost: 0.25, PromptTokens: 10},
+		"task2": {SessionID: "sub2", ToolCallID: "task2", Cost: 0.5, PromptTokens: 10},
+	}, costs)
+}
@@ -0,0 +1,159 @@
+package mix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// agentServer fakes the API of one session. It accepts messages, streams the
+// events the test pushes to every open stream, and records the requests it
+// answers other than streams.
+type agentServer struct {
+	// reply is called in its own goroutine once a message is accepted, to push
+	// the events of its turn.
+	reply func(a *agentServer, text string)
+
+	mu       sync.Mutex
+	streams  map[chan string]struct{}
+	seq      int
+	requests []string
+	session  string
+}
+
+func newAgentServer(t *testing.T, reply func(a *agentServer, text string), opts ...SDKOption) (*agentServer, *Mix) {
+	t.Helper()
+	a := &agentServer{
+		reply:   reply,
+		streams: map[chan string]struct{}{},
+		session: `{"id":"s1","title":"test","createdAt":"2026-01-01T00:00:00Z"}`,
+	}
+	ts := httptest.NewServer(a)
+	t.Cleanup(ts.Close)
+	return a, New(ts.URL, opts...)
+}
+
+func (a *agentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	writeJSON := func(status int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+
+	switch {
+	case path == "/stream":
+		a.stream(w, r)
+	case path == "/api/sessions/s1/messages" && r.Method == http.MethodPost:
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		a.record("message:" + body.Text)
+		writeJSON(http.StatusAccepted, `{"sessionId":"s1","status":"processing"}`)
+		if a.reply != nil {
+			go a.reply(a, body.Text)
+		}
+	case path == "/api/sessions/s1/messages":
+		writeJSON(http.StatusOK, `[]`)
+	case path == "/api/sessions/s1/cancel":
+		a.record("cancel")
+		writeJSON(http.StatusOK, `{"cancelled":true}`)
+	case path == "/api/sessions/s1":
+		a.mu.Lock()
+		session := a.session
+		a.mu.Unlock()
+		if session == "" {
+			writeJSON(http.StatusNotFound, `{"error":{"code":404,"message":"not found","type":"not_found"}}`)
+			return
+		}
+		writeJSON(http.StatusOK, session)
+	case strings.HasPrefix(path, "/api/permissions/"):
+		a.record("permission:" + strings.TrimPrefix(path, "/api/permissions/"))
+		writeJSON(http.StatusOK, `{}`)
+	case strings.HasPrefix(path, "/api/notifications/"):
+		body, _ := io.ReadAll(r.Body)
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/api/notifications/"), "/respond")
+		a.record("notification:" + id + " " + string(body))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *agentServer) stream(w http.ResponseWriter, r *http.Request) {
+	frames := make(chan string, 100)
+	a.mu.Lock()
+	a.streams[frames] = struct{}{}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.streams, frames)
+		a.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case frame := <-frames:
+			io.WriteString(w, frame)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// push sends an event to every open stream.
+func (a *agentServer) push(event, data string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	frame := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", a.seq, event, data)
+	for frames := range a.streams {
+		frames <- frame
+	}
+}
+
+func (a *agentServer) userMessage(text string) {
+	a.push("user_message_created", fmt.Sprintf(`{"messageId":%q,"content":%q,"sessionId":"s1","type":"user_message_created"}`, "u-"+text, text))
+}
+
+func (a *agentServer) content(text string) {
+	a.push("content", fmt.Sprintf(`{"content":%q,"type":"content"}`, text))
+}
+
+func (a *agentServer) complete(messageID string) {
+	a.push("complete", fmt.Sprintf(`{"done":true,"type":"complete","messageId":%q}`, messageID))
+}
+
+func (a *agentServer) record(request string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = append(a.requests, request)
+}
+
+func (a *agentServer) received() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+// waitFor waits until the server received request.
+func (a *agentServer) waitFor(t *testing.T, request string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return slices.Contains(a.received(), request)
+	}, 5*time.Second, 5*time.Millisecond, "waiting for %s in %v", request, a.received())
+}
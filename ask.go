@@ -0,0 +1,212 @@
+package mix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+// DefaultAskRetries is how many corrective follow-up messages Ask sends when
+// a reply does not match the requested shape.
+const DefaultAskRetries = 2
+
+// Validator may be implemented by types decoded by Ask to reject replies that
+// match the schema but are not acceptable. The error is sent back to the
+// agent in the corrective follow-up message.
+type Validator interface {
+	Validate() error
+}
+
+// AskError is returned by Ask when no reply could be decoded after all
+// attempts.
+type AskError struct {
+	SessionID string
+	// Number of messages sent, including the initial prompt
+	Attempts int
+	// Content of the last reply
+	Content string
+	// Why the last reply was rejected
+	Err error
+}
+
+var _ error = &AskError{}
+
+func (e *AskError) Error() string {
+	return fmt.Sprintf("session %s: no valid structured reply after %d attempts: %v", e.SessionID, e.Attempts, e.Err)
+}
+
+func (e *AskError) Unwrap() error {
+	return e.Err
+}
+
+type askOptions struct {
+	retries  int
+	waitOpts []WaitOption
+	body     operations.SendMessageRequestBody
+}
+
+type AskOption func(*askOptions)
+
+// WithAskRetries sets how many corrective follow-up messages are sent. The
+// default is DefaultAskRetries; zero sends the prompt only once.
+func WithAskRetries(n int) AskOption {
+	return func(o *askOptions) {
+		o.retries = n
+	}
+}
+
+// WithAskWaitOptions applies wait options, such as permission handlers or a
+// timeout, to every message sent by Ask.
+func WithAskWaitOptions(opts ...WaitOption) AskOption {
+	return func(o *askOptions) {
+		o.waitOpts = append(o.waitOpts, opts...)
+	}
+}
+
+// WithAskRequest sets the request fields other than Text, such as MaxSteps or
+// PlanMode, used for every message sent by Ask.
+func WithAskRequest(body operations.SendMessageRequestBody) AskOption {
+	return func(o *askOptions) {
+		o.body = body
+	}
+}
+
+// Ask sends prompt to a session with instructions to reply with JSON matching
+// the schema derived from T, and decodes the reply into T. The JSON is taken
+// from the complete event's content, either bare, in a fenced code block, or
+// embedded in surrounding text. When a reply cannot be decoded or fails
+// validation, Ask sends a follow-up message describing the problem and tries
+// again, up to the configured number of retries.
+//
+// Struct fields follow encoding/json tags. Fields without omitempty that are
+// not pointers are required. A `description` tag documents a field for the
+// agent and an `enum` tag restricts a string field to comma-separated values.
+func Ask[T any](ctx context.Context, messages *Messages, id string, prompt string, opts ...AskOption) (T, error) {
+	var zero T
+
+	o := askOptions{retries: DefaultAskRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	schema, err := schemaFor(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return zero, fmt.Errorf("deriving JSON schema: %w", err)
+	}
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return zero, err
+	}
+
+	body := o.body
+	body.Text = fmt.Sprintf("%s\n\nRespond with only a JSON value matching this JSON Schema, without any other text:\n```json\n%s\n```", prompt, schemaJSON)
+
+	for attempt := 1; ; attempt++ {
+		res, err := messages.SendMessageAndWait(ctx, id, body, o.waitOpts...)
+		if err != nil {
+			return zero, err
+		}
+
+		v, err := decodeReply[T](res.Content, schema)
+		if err == nil {
+			return v, nil
+		}
+		if attempt > o.retries {
+			return zero, &AskError{SessionID: id, Attempts: attempt, Content: res.Content, Err: err}
+		}
+
+		body.Text = fmt.Sprintf("Your reply could not be used: %v\n\nRespond again with only a JSON value matching the JSON Schema given above, without any other text.", err)
+	}
+}
+
+// decodeReply extracts the JSON value from a reply, checks it against the
+// schema and decodes it.
+func decodeReply[T any](content string, schema *jsonSchema) (T, error) {
+	var v T
+
+	raw, err := extractJSON(content, schema.Type)
+	if err != nil {
+		return v, err
+	}
+
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return v, err
+	}
+	if err := schema.validate(generic, ""); err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, err
+	}
+	if validator, ok := any(&v).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+var errNoJSON = errors.New("the reply did not contain a JSON value")
+
+// extractJSON finds the JSON value in a reply: the whole reply, the first
+// fenced code block holding valid JSON, or the first valid JSON object or
+// array embedded in the text. want is the schema type, used to prefer an
+// object or an array.
+func extractJSON(content, want string) (json.RawMessage, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errNoJSON
+	}
+	if json.Valid([]byte(content)) {
+		return json.RawMessage(content), nil
+	}
+
+	rest := content
+	for {
+		start := strings.Index(rest, "```")
+		if start < 0 {
+			break
+		}
+		block := rest[start+3:]
+		end := strings.Index(block, "```")
+		if end < 0 {
+			break
+		}
+		code := block[:end]
+		// Drop the info string, such as json.
+		if nl := strings.IndexByte(code, '\n'); nl >= 0 && !strings.ContainsAny(code[:nl], "{[") {
+			code = code[nl+1:]
+		}
+		if code = strings.TrimSpace(code); json.Valid([]byte(code)) {
+			return json.RawMessage(code), nil
+		}
+		rest = block[end+3:]
+	}
+
+	open := "{["
+	switch want {
+	case "object":
+		open = "{"
+	case "array":
+		open = "["
+	}
+	for i := 0; i < len(content); i++ {
+		if strings.IndexByte(open, content[i]) < 0 {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(content[i:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == nil {
+			return bytes.TrimSpace(raw), nil
+		}
+	}
+	return nil, errNoJSON
+}
@@ -0,0 +1,153 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractJSON(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantRaw string
+	}{
+		{"bare object", ` {"a":1} `, "object", `{"a":1}`},
+		{"bare string", `"yes"`, "string", `"yes"`},
+		{"fenced with info string", "Here you go:\n```json\n{\"a\":1}\n```\nDone.", "object", `{"a":1}`},
+		{"fenced without info string", "```\n[1,2]\n```", "array", `[1,2]`},
+		{"fenced on one line", "```{\"a\":1}```", "object", `{"a":1}`},
+		{"skips invalid fenced block", "```go\nfmt.Println()\n```\n```json\n{\"a\":1}\n```", "object", `{"a":1}`},
+		{"prose around object", `The answer is {"a":{"b":[1]}} as requested.`, "object", `{"a":{"b":[1]}}`},
+		{"skips unbalanced brace", `Use {braces} like {"a":1}.`, "object", `{"a":1}`},
+		{"prefers array", `Options [1,2] in {"a":[3]}`, "array", `[1,2]`},
+		{"prefers object", `Options [1,2] in {"a":[3]}`, "object", `{"a":[3]}`},
+		{"first of either", `Options [1,2] in {"a":[3]}`, "", `[1,2]`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			raw, err := extractJSON(tt.content, tt.want)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRaw, string(raw))
+		})
+	}
+}
+
+func TestExtractJSON_None(t *testing.T) {
+	t.Parallel()
+	for _, content := range []string{"", "   ", "no JSON here", "almost {json", "```json\n{oops}\n```"} {
+		_, err := extractJSON(content, "object")
+		assert.ErrorIs(t, err, errNoJSON, content)
+	}
+}
+
+type askVerdict struct {
+	Verdict string `json:"verdict" enum:"pass,fail"`
+	Score   int    `json:"score"`
+}
+
+func (v *askVerdict) Validate() error {
+	if v.Score < 0 {
+		return errors.New("score must not be negative")
+	}
+	return nil
+}
+
+func TestDecodeReply(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		content string
+		want    askVerdict
+		wantErr string
+	}{
+		{"valid", "```json\n{\"verdict\":\"pass\",\"score\":3}\n```", askVerdict{Verdict: "pass", Score: 3}, ""},
+		{"no JSON", "I think it passes.", askVerdict{}, errNoJSON.Error()},
+		{"type mismatch", `{"verdict":"pass","score":"3"}`, askVerdict{}, "/score: must be an integer, got string"},
+		{"enum", `{"verdict":"maybe","score":3}`, askVerdict{}, "/verdict: must be one of [pass fail]"},
+		{"validator", `{"verdict":"fail","score":-1}`, askVerdict{}, "score must not be negative"},
+	}
+	schema, err := schemaFor(reflect.TypeOf(askVerdict{}))
+	require.NoError(t, err)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := decodeReply[askVerdict](tt.content, schema)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// askServer replies to each message with the next of replies.
+func askServer(t *testing.T, replies ...string) (*agentServer, *Mix) {
+	t.Helper()
+	var mu sync.Mutex
+	return newAgentServer(t, func(a *agentServer, text string) {
+		mu.Lock()
+		reply := replies[0]
+		if len(replies) > 1 {
+			replies = replies[1:]
+		}
+		mu.Unlock()
+		a.userMessage(text)
+		a.content(reply)
+		a.complete("m1")
+	})
+}
+
+func TestAsk_RetriesRejectedReply(t *testing.T) {
+	t.Parallel()
+	srv, client := askServer(t,
+		`Sure: {"verdict":"pass","score":"high"}`,
+		"```json\n{\"verdict\":\"pass\",\"score\":9}\n```")
+
+	got, err := Ask[askVerdict](context.Background(), client.Messages, "s1", "Grade it.", WithAskWaitOptions(WithoutUsage()))
+	require.NoError(t, err)
+	assert.Equal(t, askVerdict{Verdict: "pass", Score: 9}, got)
+
+	sent := sentMessages(srv)
+	require.Len(t, sent, 2)
+	assert.True(t, strings.HasPrefix(sent[0], "Grade it.\n\n"))
+	assert.Contains(t, sent[0], `"enum": [`)
+	assert.Equal(t, "Your reply could not be used: /score: must be an integer, got string\n\nRespond again with only a JSON value matching the JSON Schema given above, without any other text.", sent[1])
+}
+
+func TestAsk_GivesUp(t *testing.T) {
+	t.Parallel()
+	srv, client := askServer(t, "I would rather not.")
+
+	_, err := Ask[askVerdict](context.Background(), client.Messages, "s1", "Grade it.",
+		WithAskRetries(1), WithAskWaitOptions(WithoutUsage()))
+	var askErr *AskError
+	require.ErrorAs(t, err, &askErr)
+	assert.Equal(t, "s1", askErr.SessionID)
+	assert.Equal(t, 2, askErr.Attempts)
+	assert.Equal(t, "I would rather not.", askErr.Content)
+	assert.ErrorIs(t, err, errNoJSON)
+	assert.Len(t, sentMessages(srv), 2)
+}
+
+func TestAsk_UnsupportedType(t *testing.T) {
+	t.Parallel()
+	srv, client := askServer(t, "{}")
+
+	_, err := Ask[map[int]string](context.Background(), client.Messages, "s1", "Map it.")
+	assert.ErrorContains(t, err, "deriving JSON schema")
+	assert.Empty(t, srv.received())
+}
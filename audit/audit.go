@@ -0,0 +1,394 @@
+// Package audit keeps a tamper-evident record of the permission requests and
+// notifications raised by agents and of the decisions sent back. Records are
+// appended to a JSONL file in which every record carries the hash of the one
+// before it, so that edits, insertions and deletions can be detected with
+// Verify.
+//
+// By default records are hashed with plain SHA-256, which anyone able to write
+// the file can recompute after editing it, so Verify alone only catches
+// accidental corruption. To detect deliberate tampering, hash records with a
+// secret key through WithKey, or keep the head returned by Log.Head outside
+// the file, where the writer cannot change it, and check it with VerifyHead.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kind classifies a record.
+type Kind string
+
+const (
+	// KindPermissionRequest records a permission event.
+	KindPermissionRequest Kind = "permission_request"
+	// KindPermissionDecision records a grant or deny sent for a request.
+	KindPermissionDecision Kind = "permission_decision"
+	// KindPermissionUnsent records a grant or deny that was decided but could
+	// not be sent, so the request may still be pending on the server.
+	KindPermissionUnsent Kind = "permission_unsent"
+	// KindNotification records a notification event.
+	KindNotification Kind = "notification"
+	// KindNotificationResponse records a response sent to a notification.
+	KindNotificationResponse Kind = "notification_response"
+)
+
+// GenesisHash is the Prev of the first record of a log.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Record is one line of an audit log.
+type Record struct {
+	// Position in the log, starting at 1
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	Kind Kind      `json:"kind"`
+	// Session the request was raised in
+	SessionID string `json:"sessionId,omitempty"`
+	// ID of the permission request or notification
+	RequestID string `json:"requestId"`
+	// Who made the decision, such as a user name or "policy"
+	Decider string `json:"decider,omitempty"`
+	// allow or deny for permissions, the response type for notifications
+	Decision string `json:"decision,omitempty"`
+	// Text or choice sent in a notification response
+	Value *string `json:"value,omitempty"`
+	// Additional context, such as the matching policy rule
+	Reason string `json:"reason,omitempty"`
+	// Payload of the originating event
+	Event json.RawMessage `json:"event,omitempty"`
+	// Hash of the previous record
+	Prev string `json:"prev"`
+	// SHA-256 of this record without the hash, or its HMAC-SHA-256 with the
+	// key of WithKey, hex encoded
+	Hash string `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the record with its Hash field cleared,
+// keyed with key unless it is nil.
+func (r Record) computeHash(key []byte) (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	if key == nil {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+type options struct {
+	sync    bool
+	now     func() time.Time
+	onError func(err error)
+	key     []byte
+}
+
+// Option configures a Log.
+type Option func(*options)
+
+// WithSync flushes every record to stable storage before Append returns, when
+// the log writes to a file.
+func WithSync() Option {
+	return func(o *options) {
+		o.sync = true
+	}
+}
+
+// WithClock overrides the source of record timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithErrorHandler registers a handler called when an adapter such as
+// DecisionHandler or PromptHandler fails to record, since those cannot return
+// an error to their caller.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(o *options) {
+		o.onError = handler
+	}
+}
+
+// WithKey hashes records with HMAC-SHA-256 under key instead of plain SHA-256,
+// so that records cannot be edited and rehashed without the key. The same key
+// must be passed to Open, Verify and VerifyHead.
+func WithKey(key []byte) Option {
+	return func(o *options) {
+		o.key = append([]byte{}, key...)
+	}
+}
+
+// Log appends hash-chained records to a writer. It is safe for concurrent use.
+type Log struct {
+	w    io.Writer
+	opts options
+
+	mu   sync.Mutex
+	seq  int64
+	prev string
+	// session of each request and notification recorded, by ID
+	requests map[string]string
+	err      error
+}
+
+// New starts a new log on w.
+func New(w io.Writer, opts ...Option) *Log {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Log{w: w, opts: o, prev: GenesisHash, requests: map[string]string{}}
+}
+
+// Open opens the log at path for appending, creating it if needed. An
+// existing log is verified first and extended from its last record, with the
+// requests it holds known, so that redelivered events are still recorded
+// once; Open fails if it does not verify.
+func Open(path string, opts ...Option) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := New(f, opts...)
+	report, _, err := verify(f, 0, l.opts.key, l.track)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	l.seq, l.prev = report.Records, report.Head
+	return l, nil
+}
+
+// Append completes a record with its sequence number, timestamp if unset,
+// and hashes, and writes it. Decisions and responses recorded without a
+// session take it from their request. A failed write poisons the log: the
+// chain on disk may be incomplete, so later appends fail too.
+func (l *Log) Append(rec Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.append(rec)
+}
+
+// appendRequest appends a request or notification record unless one with the
+// same ID was recorded already, so that redelivered events are logged once.
+func (l *Log) appendRequest(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.requests[rec.RequestID]; ok {
+		return nil
+	}
+	_, err := l.append(rec)
+	return err
+}
+
+func (l *Log) append(rec Record) (Record, error) {
+	if l.err != nil {
+		return Record{}, l.err
+	}
+
+	rec.Seq = l.seq + 1
+	if rec.Time.IsZero() {
+		rec.Time = l.opts.now()
+	}
+	rec.Time = rec.Time.UTC()
+	if rec.SessionID == "" {
+		rec.SessionID = l.requests[rec.RequestID]
+	}
+	if len(rec.Event) > 0 {
+		// Re-encoding compacts the payload, so that the hash survives a
+		// round trip through the file.
+		event, err := json.Marshal(rec.Event)
+		if err != nil {
+			return Record{}, fmt.Errorf("audit: invalid event payload: %w", err)
+		}
+		rec.Event = event
+	}
+	rec.Prev = l.prev
+
+	hash, err := rec.computeHash(l.opts.key)
+	if err != nil {
+		return Record{}, err
+	}
+	rec.Hash = hash
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, err
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		l.err = fmt.Errorf("audit: writing record %d: %w", rec.Seq, err)
+		return Record{}, l.err
+	}
+	if f, ok := l.w.(*os.File); ok && l.opts.sync {
+		if err := f.Sync(); err != nil {
+			l.err = fmt.Errorf("audit: syncing record %d: %w", rec.Seq, err)
+			return Record{}, l.err
+		}
+	}
+
+	l.seq, l.prev = rec.Seq, rec.Hash
+	l.track(rec)
+	return rec, nil
+}
+
+// track remembers the session of a request or notification record.
+func (l *Log) track(rec Record) {
+	if rec.Kind == KindPermissionRequest || rec.Kind == KindNotification {
+		l.requests[rec.RequestID] = rec.SessionID
+	}
+}
+
+// Head returns the sequence number and hash of the last record. Keeping them
+// outside the log, for example in a database, lets Verify detect truncation.
+func (l *Log) Head() (int64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.prev
+}
+
+// reportError passes a failure of an adapter to the error handler, if any.
+func (l *Log) reportError(err error) {
+	if err != nil && l.opts.onError != nil {
+		l.opts.onError(err)
+	}
+}
+
+// Err returns the error that stopped the log, if any.
+func (l *Log) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (l *Log) Close() error {
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Report summarizes a verified log.
+type Report struct {
+	// Number of records
+	Records int64
+	// Hash of the last record, or GenesisHash for an empty log
+	Head string
+}
+
+// VerifyError locates the first record that breaks the chain.
+type VerifyError struct {
+	// Line number in the file, starting at 1
+	Line   int
+	Seq    int64
+	Reason string
+}
+
+var _ error = &VerifyError{}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("audit: line %d: %s", e.Line, e.Reason)
+}
+
+// ErrTruncated is returned by VerifyHead when the log ends before the
+// expected head.
+var ErrTruncated = errors.New("audit: log is truncated")
+
+// Verify checks that every record of a log is intact and chained to the one
+// before it, starting from GenesisHash. It detects edited, inserted, removed
+// and reordered records, removal of leading records, and a partially written
+// last line. Removal of trailing records leaves a valid, shorter chain; use
+// VerifyHead to detect it. A log written with WithKey needs the same option;
+// other options are ignored. Without a key, Verify cannot tell a deliberate
+// edit whose hashes were recomputed from an intact log.
+func Verify(r io.Reader, opts ...Option) (Report, error) {
+	report, _, err := verify(r, 0, verifyKey(opts), nil)
+	return report, err
+}
+
+// VerifyHead verifies a log and checks that it still contains the record with
+// the given sequence number and hash, as returned by Log.Head at an earlier
+// time.
+func VerifyHead(r io.Reader, seq int64, hash string, opts ...Option) (Report, error) {
+	report, found, err := verify(r, seq, verifyKey(opts), nil)
+	if err != nil {
+		return report, err
+	}
+	if report.Records < seq {
+		return report, fmt.Errorf("%w: %d records, want at least %d", ErrTruncated, report.Records, seq)
+	}
+	if found != hash {
+		return report, &VerifyError{Line: int(seq), Seq: seq, Reason: "record hash does not match the expected head"}
+	}
+	return report, nil
+}
+
+func verifyKey(opts []Option) []byte {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.key
+}
+
+// verify checks a log, also returning the hash of the record at seq. visit,
+// if not nil, is called with every record that verifies.
+func verify(r io.Reader, seq int64, key []byte, visit func(Record)) (Report, string, error) {
+	report := Report{Head: GenesisHash}
+	found := GenesisHash
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				return report, found, &VerifyError{Line: line, Reason: "incomplete last record"}
+			}
+			return report, found, nil
+		}
+		if err != nil {
+			return report, found, err
+		}
+
+		var rec Record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return report, found, &VerifyError{Line: line, Reason: fmt.Sprintf("malformed record: %v", err)}
+		}
+		if rec.Seq != report.Records+1 {
+			return report, found, &VerifyError{Line: line, Seq: rec.Seq, Reason: fmt.Sprintf("sequence %d, want %d", rec.Seq, report.Records+1)}
+		}
+		if rec.Prev != report.Head {
+			return report, found, &VerifyError{Line: line, Seq: rec.Seq, Reason: "previous hash does not match the preceding record"}
+		}
+		hash, err := rec.computeHash(key)
+		if err != nil {
+			return report, found, err
+		}
+		if hash != rec.Hash {
+			return report, found, &VerifyError{Line: line, Seq: rec.Seq, Reason: "record hash does not match its contents"}
+		}
+
+		report.Records, report.Head = rec.Seq, rec.Hash
+		if rec.Seq == seq {
+			found = rec.Hash
+		}
+		if visit != nil {
+			visit(rec)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+func permissionEvent(id string) *components.SSEEventStream {
+	event := components.CreateSSEEventStreamPermission(components.SSEPermissionEvent{
+		Data: components.SSEPermissionEventData{
+			ID:          id,
+			SessionID:   "s1",
+			ToolName:    components.CreateToolNameStr("Bash"),
+			Action:      "execute",
+			Description: "run <make test>",
+		},
+	})
+	return &event
+}
+
+func writeLog(t *testing.T) (*bytes.Buffer, *Log) {
+	t.Helper()
+	var buf bytes.Buffer
+	l := New(&buf, WithClock(func() time.Time { return time.Unix(1700000000, 0) }))
+
+	require.NoError(t, l.Observe(permissionEvent("p1")))
+	require.NoError(t, l.Observe(permissionEvent("p1")))
+	require.NoError(t, l.RecordPermissionDecision("", "p1", "alice", true, ""))
+
+	text := "yes"
+	handler := l.NotificationHandler("bob", func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+		return &operations.RespondToNotificationRequestBody{Type: operations.TypeText, Value: &text}, nil
+	})
+	_, err := handler(context.Background(), components.SSENotificationEventData{ID: "n1", SessionID: "s1", ResponseType: components.ResponseTypeText})
+	require.NoError(t, err)
+	return &buf, l
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+	buf, l := writeLog(t)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 4, "a redelivered event is recorded once")
+	assert.Contains(t, lines[1], `"kind":"permission_decision","sessionId":"s1","requestId":"p1","decider":"alice","decision":"allow"`)
+	assert.Contains(t, lines[3], `"decider":"bob","decision":"text","value":"yes"`)
+
+	report, err := Verify(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	seq, head := l.Head()
+	assert.Equal(t, Report{Records: 4, Head: head}, report)
+	assert.EqualValues(t, 4, seq)
+}
+
+func TestVerify_Tampering(t *testing.T) {
+	t.Parallel()
+	buf, l := writeLog(t)
+	seq, head := l.Head()
+	lines := strings.SplitAfter(buf.String(), "\n")[:4]
+
+	tests := []struct {
+		name   string
+		log    string
+		reason string
+		line   int
+	}{
+		{"edited", strings.Replace(buf.String(), `"decision":"allow"`, `"decision":"deny"`, 1), "record hash", 2},
+		{"removed", lines[0] + lines[2] + lines[3], "sequence 3, want 2", 2},
+		{"reordered", lines[1] + lines[0] + lines[2] + lines[3], "sequence 2, want 1", 1},
+		{"partial write", buf.String() + `{"seq":5`, "incomplete last record", 5},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Verify(strings.NewReader(tt.log))
+			var verr *VerifyError
+			require.ErrorAs(t, err, &verr)
+			assert.Contains(t, verr.Reason, tt.reason)
+			assert.Equal(t, tt.line, verr.Line)
+		})
+	}
+
+	truncated := lines[0] + lines[1]
+	_, err := Verify(strings.NewReader(truncated))
+	assert.NoError(t, err, "a truncated log is a valid chain")
+	_, err = VerifyHead(strings.NewReader(truncated), seq, head)
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, err = VerifyHead(strings.NewReader(buf.String()), seq, head)
+	assert.NoError(t, err)
+	_, err = VerifyHead(strings.NewReader(buf.String()), 2, head)
+	assert.ErrorContains(t, err, "expected head")
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	l, err := Open(path, WithSync())
+	require.NoError(t, err)
+	require.NoError(t, l.Observe(permissionEvent("p1")))
+	require.NoError(t, l.Close())
+
+	l, err = Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Observe(permissionEvent("p1")), "redelivered after reopening")
+	require.NoError(t, l.RecordPermissionDecision("", "p1", "alice", false, ""))
+	seq, head := l.Head()
+	require.NoError(t, l.Close())
+	assert.EqualValues(t, 2, seq)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"kind":"permission_decision","sessionId":"s1"`)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = VerifyHead(f, seq, head)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"seq":1,"prev":"x"}`+"\n"), 0o600))
+	_, err = Open(path)
+	assert.ErrorContains(t, err, "previous hash")
+}
+
+func TestLog_Key(t *testing.T) {
+	t.Parallel()
+	key := []byte("secret")
+	var buf bytes.Buffer
+	l := New(&buf, WithKey(key))
+	require.NoError(t, l.Observe(permissionEvent("p1")))
+	require.NoError(t, l.RecordPermissionDecision("", "p1", "alice", false, ""))
+
+	_, err := Verify(bytes.NewReader(buf.Bytes()), WithKey(key))
+	require.NoError(t, err)
+	_, err = Verify(bytes.NewReader(buf.Bytes()))
+	assert.ErrorContains(t, err, "does not match its contents", "unkeyed")
+	_, err = Verify(bytes.NewReader(buf.Bytes()), WithKey([]byte("guess")))
+	assert.ErrorContains(t, err, "does not match its contents", "wrong key")
+
+	// An edit rehashed without the key is detected.
+	var forged bytes.Buffer
+	prev := GenesisHash
+	for i, line := range strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var rec Record
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		if i == 1 {
+			rec.Decision = "allow"
+		}
+		rec.Prev = prev
+		rec.Hash, err = rec.computeHash(nil)
+		require.NoError(t, err)
+		prev = rec.Hash
+		data, err := json.Marshal(rec)
+		require.NoError(t, err)
+		forged.Write(append(data, '\n'))
+	}
+	_, err = Verify(bytes.NewReader(forged.Bytes()))
+	require.NoError(t, err, "an unkeyed log can be rewritten")
+	_, err = Verify(bytes.NewReader(forged.Bytes()), WithKey(key))
+	assert.ErrorContains(t, err, "line 1: record hash does not match its contents")
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+	_, err = Open(path)
+	assert.Error(t, err)
+	l, err = Open(path, WithKey(key))
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+}
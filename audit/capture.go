@@ -0,0 +1,248 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	mix "github.com/recreate-run/mix-go-sdk"
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+	"github.com/recreate-run/mix-go-sdk/policy"
+)
+
+// Deciders recorded by the policy adapters.
+const (
+	DeciderPolicy     = "policy"
+	DeciderRemembered = "remembered"
+)
+
+// Decision values recorded for permissions.
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// Observe records the request carried by a permission or notification event.
+// Other events, and requests recorded already, are ignored.
+func (l *Log) Observe(event *components.SSEEventStream) error {
+	switch {
+	case event == nil:
+		return nil
+	case event.SSEPermissionEvent != nil:
+		return l.RecordPermissionRequest(event.SSEPermissionEvent.Data)
+	case event.SSENotificationEvent != nil:
+		return l.RecordNotification(event.SSENotificationEvent.Data)
+	}
+	return nil
+}
+
+// Run records the permission and notification events from src until the
+// stream ends, ctx is done, or appending fails.
+func (l *Log) Run(ctx context.Context, src events.Source) error {
+	return events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		return l.Observe(event)
+	})
+}
+
+// RecordPermissionRequest records a permission request unless it was
+// recorded already.
+func (l *Log) RecordPermissionRequest(req components.SSEPermissionEventData) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("audit: encoding permission %s: %w", req.ID, err)
+	}
+	return l.appendRequest(Record{
+		Kind:      KindPermissionRequest,
+		SessionID: req.SessionID,
+		RequestID: req.ID,
+		Event:     data,
+	})
+}
+
+// RecordNotification records a notification unless it was recorded already.
+func (l *Log) RecordNotification(n components.SSENotificationEventData) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("audit: encoding notification %s: %w", n.ID, err)
+	}
+	return l.appendRequest(Record{
+		Kind:      KindNotification,
+		SessionID: n.SessionID,
+		RequestID: n.ID,
+		Event:     data,
+	})
+}
+
+// RecordPermissionDecision records the grant or deny sent for a permission
+// request by decider. sessionID may be empty when the request was recorded by
+// this log.
+func (l *Log) RecordPermissionDecision(sessionID, requestID, decider string, allow bool, reason string) error {
+	decision := DecisionDeny
+	if allow {
+		decision = DecisionAllow
+	}
+	_, err := l.Append(Record{
+		Kind:      KindPermissionDecision,
+		SessionID: sessionID,
+		RequestID: requestID,
+		Decider:   decider,
+		Decision:  decision,
+		Reason:    reason,
+	})
+	return err
+}
+
+// RecordPermissionUnsent records a grant or deny decided by decider that
+// could not be sent, with the failure as reason.
+func (l *Log) RecordPermissionUnsent(sessionID, requestID, decider string, allow bool, reason string) error {
+	decision := DecisionDeny
+	if allow {
+		decision = DecisionAllow
+	}
+	_, err := l.Append(Record{
+		Kind:      KindPermissionUnsent,
+		SessionID: sessionID,
+		RequestID: requestID,
+		Decider:   decider,
+		Decision:  decision,
+		Reason:    reason,
+	})
+	return err
+}
+
+// RecordNotificationResponse records the response sent to a notification by
+// decider. sessionID may be empty when the notification was recorded by this
+// log.
+func (l *Log) RecordNotificationResponse(sessionID, notificationID, decider string, body operations.RespondToNotificationRequestBody) error {
+	_, err := l.Append(Record{
+		Kind:      KindNotificationResponse,
+		SessionID: sessionID,
+		RequestID: notificationID,
+		Decider:   decider,
+		Decision:  string(body.Type),
+		Value:     body.Value,
+	})
+	return err
+}
+
+// PermissionHandler wraps handler so that each request and the decision made
+// by decider are recorded. A decision that cannot be recorded fails the
+// handler, so that it is never sent unaudited.
+func (l *Log) PermissionHandler(decider string, handler mix.PermissionHandler) mix.PermissionHandler {
+	return func(ctx context.Context, req components.SSEPermissionEventData) (bool, error) {
+		if err := l.RecordPermissionRequest(req); err != nil {
+			return false, err
+		}
+		allow, err := handler(ctx, req)
+		if err != nil {
+			return false, err
+		}
+		if err := l.RecordPermissionDecision(req.SessionID, req.ID, decider, allow, ""); err != nil {
+			return false, err
+		}
+		return allow, nil
+	}
+}
+
+// NotificationHandler wraps handler so that each notification and the
+// response made by decider are recorded. A response that cannot be recorded
+// fails the handler, so that it is never sent unaudited.
+func (l *Log) NotificationHandler(decider string, handler mix.NotificationHandler) mix.NotificationHandler {
+	return func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+		if err := l.RecordNotification(n); err != nil {
+			return nil, err
+		}
+		body, err := handler(ctx, n)
+		if err != nil || body == nil {
+			return body, err
+		}
+		if err := l.RecordNotificationResponse(n.SessionID, n.ID, decider, *body); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+}
+
+// DecisionHandler returns a policy.DecisionHandler recording the decisions of
+// a policy.Engine, then calling next if it is not nil. Decisions made by the
+// policy are recorded with DeciderPolicy and the matching rule as reason,
+// remembered ones with DeciderRemembered, and asked ones with asker. The
+// engine calls the handler after answering, so failures to record are passed
+// to the handler set with WithErrorHandler.
+func (l *Log) DecisionHandler(asker string, next policy.DecisionHandler) policy.DecisionHandler {
+	return func(outcome policy.Outcome) {
+		req := outcome.Request
+		decider, reason := engineDecider(outcome, asker)
+		err := l.RecordPermissionRequest(req)
+		if err == nil {
+			err = l.RecordPermissionDecision(req.SessionID, req.ID, decider, outcome.Result.Decision == policy.Allow, reason)
+		}
+		l.reportError(err)
+		if next != nil {
+			next(outcome)
+		}
+	}
+}
+
+// PromptHandler returns a policy.PromptHandler recording the decisions sent by
+// a policy.PromptManager on behalf of decider, then calling next if it is not
+// nil. Cancelled prompts send nothing and record only the request; defaulted
+// ones are recorded with DeciderPolicy, and those decided by the manager's
+// engine without prompting as DecisionHandler records them. Prompter failures
+// are kept in the reason. A decision that could not be sent is recorded as
+// KindPermissionUnsent with the failure as reason. The manager calls the
+// handler after answering, so failures to record are passed to the handler
+// set with WithErrorHandler.
+func (l *Log) PromptHandler(decider string, next policy.PromptHandler) policy.PromptHandler {
+	return func(outcome policy.PromptOutcome) {
+		req := outcome.Request
+		err := l.RecordPermissionRequest(req)
+		if err == nil && !outcome.Cancelled {
+			who, reason := decider, ""
+			if outcome.Policy != nil {
+				who, reason = engineDecider(*outcome.Policy, decider)
+			}
+			if outcome.Defaulted {
+				who, reason = DeciderPolicy, "no answer from "+decider
+			}
+			if outcome.Err != nil {
+				reason = strings.TrimPrefix(reason+"; "+outcome.Err.Error(), "; ")
+			}
+			allow := outcome.Answer.Decision == policy.Allow
+			if outcome.Sent {
+				err = l.RecordPermissionDecision(req.SessionID, req.ID, who, allow, reason)
+			} else {
+				err = l.RecordPermissionUnsent(req.SessionID, req.ID, who, allow, reason)
+			}
+		}
+		l.reportError(err)
+		if next != nil {
+			next(outcome)
+		}
+	}
+}
+
+// engineDecider returns the decider and reason to record for a decision of a
+// policy.Engine.
+func engineDecider(outcome policy.Outcome, asker string) (string, string) {
+	switch {
+	case outcome.Remembered:
+		return DeciderRemembered, ""
+	case outcome.Asked:
+		return asker, ""
+	case outcome.Result.Rule != nil:
+		return DeciderPolicy, "rule " + ruleName(outcome.Result.Rule)
+	default:
+		return DeciderPolicy, "default"
+	}
+}
+
+func ruleName(rule *policy.Rule) string {
+	if rule.Name != "" {
+		return fmt.Sprintf("%q", rule.Name)
+	}
+	return fmt.Sprintf("tool=%q action=%q path=%q command=%q", rule.Tool, rule.Action, rule.Path, rule.Command)
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/policy"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLog_PromptHandler(t *testing.T) {
+	t.Parallel()
+	rule := &policy.Rule{Name: "no rm", Decision: policy.Deny}
+	tests := []struct {
+		name    string
+		outcome policy.PromptOutcome
+		want    string
+	}{
+		{
+			name:    "sent",
+			outcome: policy.PromptOutcome{Answer: policy.Answer{Decision: policy.Allow}, Sent: true},
+			want:    `"kind":"permission_decision","sessionId":"s1","requestId":"p1","decider":"alice","decision":"allow"`,
+		},
+		{
+			name: "defaulted",
+			outcome: policy.PromptOutcome{
+				Answer: policy.Answer{Decision: policy.Deny}, Defaulted: true, Sent: true,
+				Err: errors.New("prompter broke"),
+			},
+			want: `"kind":"permission_decision","sessionId":"s1","requestId":"p1","decider":"policy","decision":"deny","reason":"no answer from alice; prompter broke"`,
+		},
+		{
+			name: "decided by engine",
+			outcome: policy.PromptOutcome{
+				Answer: policy.Answer{Decision: policy.Deny}, Sent: true,
+				Policy: &policy.Outcome{Result: policy.Result{Decision: policy.Deny, Rule: rule}},
+			},
+			want: `"kind":"permission_decision","sessionId":"s1","requestId":"p1","decider":"policy","decision":"deny","reason":"rule \"no rm\""`,
+		},
+		{
+			name: "unsent",
+			outcome: policy.PromptOutcome{
+				Answer: policy.Answer{Decision: policy.Allow},
+				Err:    errors.New("answering permission p1: connection refused"),
+			},
+			want: `"kind":"permission_unsent","sessionId":"s1","requestId":"p1","decider":"alice","decision":"allow","reason":"answering permission p1: connection refused"`,
+		},
+		{
+			name:    "cancelled",
+			outcome: policy.PromptOutcome{Cancelled: true, Err: errors.New("context canceled")},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			l := New(&buf)
+			var next []policy.PromptOutcome
+			handler := l.PromptHandler("alice", func(o policy.PromptOutcome) {
+				next = append(next, o)
+			})
+
+			tt.outcome.Request = permissionEvent("p1").SSEPermissionEvent.Data
+			handler(tt.outcome)
+			require.Len(t, next, 1)
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			assert.Contains(t, lines[0], `"kind":"permission_request"`)
+			if tt.want == "" {
+				assert.Len(t, lines, 1)
+				return
+			}
+			require.Len(t, lines, 2)
+			assert.Contains(t, lines[1], tt.want)
+		})
+	}
+}
+
+func TestLog_HandlerErrors(t *testing.T) {
+	t.Parallel()
+	var errs []error
+	l := New(failingWriter{}, WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	req := permissionEvent("p1").SSEPermissionEvent.Data
+
+	var called int
+	l.PromptHandler("alice", func(policy.PromptOutcome) { called++ })(policy.PromptOutcome{Request: req, Sent: true})
+	l.DecisionHandler("alice", func(policy.Outcome) { called++ })(policy.Outcome{Request: req})
+
+	assert.Equal(t, 2, called, "next is called even when recording fails")
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.ErrorContains(t, err, "disk full")
+	}
+	assert.ErrorContains(t, l.Err(), "disk full")
+}
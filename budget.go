@@ -0,0 +1,376 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// BudgetLimit names one of the limits of a Budget.
+type BudgetLimit string
+
+const (
+	BudgetCost      BudgetLimit = "cost"
+	BudgetTokens    BudgetLimit = "tokens"
+	BudgetToolCalls BudgetLimit = "tool_calls"
+	BudgetDuration  BudgetLimit = "duration"
+)
+
+// ErrBudgetExceeded matches every *BudgetExceededError with errors.Is.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// DefaultBudgetWarnAt is the fraction of a limit at which a warning is raised
+// when Budget.WarnAt is empty.
+const DefaultBudgetWarnAt = 0.8
+
+// DefaultBudgetPollInterval is how often a running BudgetGuard refreshes cost
+// and token usage from GetSession.
+const DefaultBudgetPollInterval = 10 * time.Second
+
+// Budget sets ceilings on a session. Zero limits are not enforced. Cost,
+// tokens and tool calls are session totals, which include usage from before
+// the guard was created, while the duration counts from the guard's creation.
+type Budget struct {
+	// Total session cost, as reported by SessionData.Cost
+	MaxCost float64
+	// Total prompt and completion tokens
+	MaxTokens int64
+	// Total tool calls, including those of subagents
+	MaxToolCalls int64
+	// Wall time since the guard was created
+	MaxDuration time.Duration
+	// Fractions of each limit at which a warning is raised, once each
+	WarnAt []float64
+}
+
+// BudgetUsage is the usage a BudgetGuard measures against its budget.
+type BudgetUsage struct {
+	Cost             float64
+	PromptTokens     int64
+	CompletionTokens int64
+	ToolCalls        int64
+	Elapsed          time.Duration
+}
+
+// Tokens returns the total of prompt and completion tokens.
+func (u BudgetUsage) Tokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// fraction returns how much of limit is used, or zero if limit is unset.
+func (u BudgetUsage) fraction(b Budget, limit BudgetLimit) float64 {
+	switch {
+	case limit == BudgetCost && b.MaxCost > 0:
+		return u.Cost / b.MaxCost
+	case limit == BudgetTokens && b.MaxTokens > 0:
+		return float64(u.Tokens()) / float64(b.MaxTokens)
+	case limit == BudgetToolCalls && b.MaxToolCalls > 0:
+		return float64(u.ToolCalls) / float64(b.MaxToolCalls)
+	case limit == BudgetDuration && b.MaxDuration > 0:
+		return float64(u.Elapsed) / float64(b.MaxDuration)
+	}
+	return 0
+}
+
+var budgetLimits = []BudgetLimit{BudgetCost, BudgetTokens, BudgetToolCalls, BudgetDuration}
+
+// BudgetWarning reports that usage crossed a warning threshold.
+type BudgetWarning struct {
+	SessionID string
+	Limit     BudgetLimit
+	// The WarnAt fraction that was crossed
+	Threshold float64
+	Usage     BudgetUsage
+}
+
+// BudgetWarningHandler is called once per limit and threshold crossed.
+type BudgetWarningHandler func(warning BudgetWarning)
+
+// BudgetExceededError is returned once a session exceeded a limit of its
+// budget. Processing has been cancelled on the server; Cancel reports the
+// outcome of that request.
+type BudgetExceededError struct {
+	SessionID string
+	Limit     BudgetLimit
+	Usage     BudgetUsage
+	Budget    Budget
+	Cancel    *apierrors.CancelError
+}
+
+var _ error = &BudgetExceededError{}
+
+func (e *BudgetExceededError) Error() string {
+	var used, limit string
+	switch e.Limit {
+	case BudgetCost:
+		used, limit = fmt.Sprintf("%.4f", e.Usage.Cost), fmt.Sprintf("%.4f", e.Budget.MaxCost)
+	case BudgetTokens:
+		used, limit = fmt.Sprint(e.Usage.Tokens()), fmt.Sprint(e.Budget.MaxTokens)
+	case BudgetToolCalls:
+		used, limit = fmt.Sprint(e.Usage.ToolCalls), fmt.Sprint(e.Budget.MaxToolCalls)
+	case BudgetDuration:
+		used, limit = e.Usage.Elapsed.Round(time.Millisecond).String(), e.Budget.MaxDuration.String()
+	}
+	return fmt.Sprintf("session %s: %s budget exceeded (%s of %s)", e.SessionID, e.Limit, used, limit)
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+type budgetOptions struct {
+	onWarning    BudgetWarningHandler
+	pollInterval time.Duration
+}
+
+type BudgetOption func(*budgetOptions)
+
+// WithBudgetWarningHandler registers a handler for budget warnings.
+func WithBudgetWarningHandler(handler BudgetWarningHandler) BudgetOption {
+	return func(o *budgetOptions) {
+		o.onWarning = handler
+	}
+}
+
+// WithBudgetPollInterval sets how often Run refreshes usage from GetSession
+// while a turn is in progress. The default is DefaultBudgetPollInterval.
+func WithBudgetPollInterval(d time.Duration) BudgetOption {
+	return func(o *budgetOptions) {
+		o.pollInterval = d
+	}
+}
+
+// BudgetGuard enforces a Budget on a session. Cost and tokens come from
+// GetSession, refreshed when a turn completes and periodically by Run; tool
+// calls are also counted from the stream as they start. When a limit is
+// exceeded the guard cancels processing once and reports a
+// *BudgetExceededError from then on. It is safe for concurrent use.
+type BudgetGuard struct {
+	sessions  *Sessions
+	sessionID string
+	budget    Budget
+	opts      budgetOptions
+	startedAt time.Time
+
+	// checkMu serializes Check so that processing is cancelled only once
+	checkMu   sync.Mutex
+	mu        sync.Mutex
+	usage     BudgetUsage
+	seenTools map[string]struct{}
+	warned    map[BudgetLimit]int
+	exceeded  *BudgetExceededError
+}
+
+// NewBudgetGuard reads the session's current usage and returns a guard
+// enforcing budget on it from now on. If the session is already over budget,
+// processing is cancelled right away and Err reports it.
+func (s *Sessions) NewBudgetGuard(ctx context.Context, id string, budget Budget, opts ...BudgetOption) (*BudgetGuard, error) {
+	o := budgetOptions{pollInterval: DefaultBudgetPollInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(budget.WarnAt) == 0 {
+		budget.WarnAt = []float64{DefaultBudgetWarnAt}
+	} else {
+		budget.WarnAt = append([]float64(nil), budget.WarnAt...)
+		sort.Float64s(budget.WarnAt)
+	}
+
+	g := &BudgetGuard{
+		sessions:  s,
+		sessionID: id,
+		budget:    budget,
+		opts:      o,
+		startedAt: time.Now(),
+		seenTools: map[string]struct{}{},
+		warned:    map[BudgetLimit]int{},
+	}
+
+	if err := g.Refresh(ctx); err != nil && !errors.Is(err, ErrBudgetExceeded) {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Usage returns the usage measured so far.
+func (g *BudgetGuard) Usage() BudgetUsage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	usage := g.usage
+	usage.Elapsed = time.Since(g.startedAt)
+	return usage
+}
+
+// Err returns the *BudgetExceededError once a limit was exceeded, or nil.
+func (g *BudgetGuard) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.exceeded == nil {
+		return nil
+	}
+	return g.exceeded
+}
+
+// Refresh updates cost, token and tool call usage from GetSession and
+// enforces the budget.
+func (g *BudgetGuard) Refresh(ctx context.Context) error {
+	res, err := g.sessions.GetSession(ctx, g.sessionID)
+	if err != nil {
+		return err
+	}
+	if res.SessionData != nil {
+		g.update(*res.SessionData)
+	}
+	return g.Check(ctx)
+}
+
+// Observe counts tool calls from a stream event and refreshes usage when the
+// root turn completes. It returns the *BudgetExceededError once a limit was
+// exceeded.
+func (g *BudgetGuard) Observe(ctx context.Context, event *components.SSEEventStream) error {
+	if event != nil && event.SSEToolExecutionStartEvent != nil {
+		g.mu.Lock()
+		id := event.SSEToolExecutionStartEvent.Data.ToolCallID
+		if _, ok := g.seenTools[id]; !ok {
+			g.seenTools[id] = struct{}{}
+			g.usage.ToolCalls++
+		}
+		g.mu.Unlock()
+	}
+
+	if events.IsTurnComplete(event) {
+		if err := g.Refresh(ctx); err != nil {
+			return err
+		}
+	}
+	return g.Check(ctx)
+}
+
+// Check raises warnings for thresholds crossed and, the first time a limit is
+// exceeded, cancels processing on the server.
+func (g *BudgetGuard) Check(ctx context.Context) error {
+	g.checkMu.Lock()
+	defer g.checkMu.Unlock()
+
+	if err := g.Err(); err != nil {
+		return err
+	}
+
+	usage := g.Usage()
+
+	g.mu.Lock()
+
+	var warnings []BudgetWarning
+	var exceeded *BudgetExceededError
+	for _, limit := range budgetLimits {
+		f := usage.fraction(g.budget, limit)
+		for g.warned[limit] < len(g.budget.WarnAt) && f >= g.budget.WarnAt[g.warned[limit]] {
+			warnings = append(warnings, BudgetWarning{
+				SessionID: g.sessionID,
+				Limit:     limit,
+				Threshold: g.budget.WarnAt[g.warned[limit]],
+				Usage:     usage,
+			})
+			g.warned[limit]++
+		}
+		if f > 1 && exceeded == nil {
+			exceeded = &BudgetExceededError{
+				SessionID: g.sessionID,
+				Limit:     limit,
+				Usage:     usage,
+				Budget:    g.budget,
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	if g.opts.onWarning != nil {
+		for _, w := range warnings {
+			g.opts.onWarning(w)
+		}
+	}
+
+	if exceeded == nil {
+		return nil
+	}
+	exceeded.Cancel = g.sessions.rootSDK.Messages.cancelProcessing(ctx, g.sessionID, ErrBudgetExceeded)
+
+	g.mu.Lock()
+	g.exceeded = exceeded
+	g.mu.Unlock()
+	return exceeded
+}
+
+// Run enforces the budget on events from src until a limit is exceeded, ctx
+// is done, or the stream ends. While it runs, usage is also refreshed every
+// poll interval and the duration limit is enforced even if no events arrive.
+// Once a limit was exceeded, Run returns the *BudgetExceededError even if ctx
+// is done or the stream failed as well.
+func (g *BudgetGuard) Run(ctx context.Context, src events.Source) error {
+	watchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go g.watch(watchCtx)
+
+	err := events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		if err := g.Observe(ctx, event); errors.Is(err, ErrBudgetExceeded) {
+			return err
+		}
+		return g.Err()
+	})
+	if exceeded := g.Err(); exceeded != nil {
+		return exceeded
+	}
+	return err
+}
+
+// watch enforces the budget between events. Once processing is cancelled the
+// server ends the turn, which unblocks Run.
+func (g *BudgetGuard) watch(ctx context.Context) {
+	var deadline <-chan time.Time
+	if g.budget.MaxDuration > 0 {
+		timer := time.NewTimer(time.Until(g.startedAt.Add(g.budget.MaxDuration)) + time.Millisecond)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var poll <-chan time.Time
+	if g.opts.pollInterval > 0 {
+		ticker := time.NewTicker(g.opts.pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			err = g.Check(ctx)
+		case <-poll:
+			err = g.Refresh(ctx)
+		}
+		if errors.Is(err, ErrBudgetExceeded) {
+			return
+		}
+	}
+}
+
+func (g *BudgetGuard) update(session components.SessionData) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.usage.Cost = session.Cost
+	g.usage.PromptTokens = session.PromptTokens
+	g.usage.CompletionTokens = session.CompletionTokens
+	// The server count includes the tool calls counted from the stream once
+	// it catches up with them.
+	g.usage.ToolCalls = max(g.usage.ToolCalls, session.ToolCallCount)
+}
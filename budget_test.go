@@ -0,0 +1,141 @@
+package mix
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+// setUsage sets the usage the server reports for the session.
+func (a *agentServer) setUsage(cost float64, promptTokens, completionTokens int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.session = fmt.Sprintf(`{"id":"s1","title":"test","createdAt":"2026-01-01T00:00:00Z","cost":%g,"promptTokens":%d,"completionTokens":%d}`,
+		cost, promptTokens, completionTokens)
+}
+
+func TestBudgetGuard_Run(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	guard, err := client.Sessions.NewBudgetGuard(ctx, "s1", Budget{MaxToolCalls: 1}, WithBudgetPollInterval(time.Hour))
+	require.NoError(t, err)
+	sub, err := client.Streaming.Subscribe(ctx, "s1")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- guard.Run(ctx, sub) }()
+
+	// Wait for the stream to open before pushing.
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return len(srv.streams) > 0
+	}, 5*time.Second, 5*time.Millisecond)
+	srv.push("tool_execution_start", `{"toolCallId":"t1","toolName":"Bash","progress":"running","type":"tool_execution_start"}`)
+	srv.push("tool_execution_start", `{"toolCallId":"t2","toolName":"Bash","progress":"running","type":"tool_execution_start"}`)
+
+	err = <-done
+	var exceeded *BudgetExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, BudgetToolCalls, exceeded.Limit)
+	assert.Equal(t, err, guard.Err())
+	assert.Equal(t, []string{"cancel"}, srv.received())
+}
+
+func TestBudgetGuard_Limits(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		budget Budget
+		limit  BudgetLimit
+		want   string
+	}{
+		{"cost", Budget{MaxCost: 2}, BudgetCost, "cost budget exceeded (2.5000 of 2.0000)"},
+		{"tokens", Budget{MaxTokens: 100}, BudgetTokens, "tokens budget exceeded (110 of 100)"},
+		{"duration", Budget{MaxDuration: time.Millisecond}, BudgetDuration, "duration budget exceeded"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, client := newAgentServer(t, nil)
+			srv.setUsage(2.5, 60, 50)
+			ctx := context.Background()
+
+			guard, err := client.Sessions.NewBudgetGuard(ctx, "s1", tt.budget)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool { return guard.Check(ctx) != nil }, 5*time.Second, time.Millisecond)
+
+			var exceeded *BudgetExceededError
+			require.ErrorAs(t, guard.Err(), &exceeded)
+			assert.Equal(t, tt.limit, exceeded.Limit)
+			assert.Contains(t, exceeded.Error(), tt.want)
+			assert.Equal(t, []string{"cancel"}, srv.received(), "cancelled once")
+			assert.Equal(t, exceeded, guard.Check(ctx))
+			assert.Equal(t, []string{"cancel"}, srv.received())
+		})
+	}
+}
+
+func TestBudgetGuard_Warnings(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, nil)
+	srv.setUsage(6, 0, 0)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var warnings []BudgetWarning
+	guard, err := client.Sessions.NewBudgetGuard(ctx, "s1", Budget{MaxCost: 10, WarnAt: []float64{0.9, 0.5}},
+		WithBudgetWarningHandler(func(w BudgetWarning) {
+			mu.Lock()
+			defer mu.Unlock()
+			warnings = append(warnings, w)
+		}))
+	require.NoError(t, err)
+
+	srv.setUsage(9.5, 0, 0)
+	require.NoError(t, guard.Refresh(ctx))
+	require.NoError(t, guard.Refresh(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, warnings, 2, "each threshold is reported once")
+	assert.Equal(t, BudgetCost, warnings[0].Limit)
+	assert.Equal(t, 0.5, warnings[0].Threshold)
+	assert.Equal(t, 6.0, warnings[0].Usage.Cost)
+	assert.Equal(t, 0.9, warnings[1].Threshold)
+	assert.Equal(t, 9.5, warnings[1].Usage.Cost)
+	assert.Empty(t, srv.received())
+}
+
+func TestSendMessageAndWait_BudgetCost(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		a.content("expensive")
+		a.setUsage(1.5, 0, 0)
+		a.complete("m1")
+	})
+
+	guard, err := client.Sessions.NewBudgetGuard(context.Background(), "s1", Budget{MaxCost: 1})
+	require.NoError(t, err)
+
+	_, err = client.Messages.SendMessageAndWait(context.Background(), "s1", operations.SendMessageRequestBody{Text: "hi"},
+		WithWaitTimeout(5*time.Second), WithBudget(guard))
+	var exceeded *BudgetExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, BudgetCost, exceeded.Limit)
+	assert.Equal(t, 1.5, exceeded.Usage.Cost)
+	assert.Contains(t, srv.received(), "cancel")
+}
@@ -0,0 +1,31 @@
+package mix
+
+import (
+	"context"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+)
+
+// cancelTimeout bounds the best-effort cancel request sent when a caller stops
+// waiting on a session.
+const cancelTimeout = 5 * time.Second
+
+// cancelProcessing asks the server to stop processing a session because the
+// caller stopped waiting for cause. It runs on a context of its own, keeping
+// only the values of ctx, since ctx is usually already done.
+func (s *Messages) cancelProcessing(ctx context.Context, id string, cause error) *apierrors.CancelError {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+
+	cancelErr := &apierrors.CancelError{SessionID: id, Cause: cause}
+	res, err := s.CancelSessionProcessing(ctx, id)
+	if err != nil {
+		cancelErr.Err = err
+		return cancelErr
+	}
+	if res.Object != nil && res.Object.Cancelled != nil {
+		cancelErr.Acknowledged = *res.Object.Cancelled
+	}
+	return cancelErr
+}
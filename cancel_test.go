@@ -0,0 +1,118 @@
+package mix
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+// deadlineClient records the deadline of the cancel requests it sends.
+type deadlineClient struct {
+	mu        sync.Mutex
+	deadlines []time.Time
+}
+
+func (c *deadlineClient) Do(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, "/cancel") {
+		deadline, _ := req.Context().Deadline()
+		c.mu.Lock()
+		c.deadlines = append(c.deadlines, deadline)
+		c.mu.Unlock()
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func TestSendMessageAndWait_CancelOnContextDone(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		sdkOpts  []SDKOption
+		waitOpts []WaitOption
+	}{
+		{"SDK option", []SDKOption{WithCancelOnContextDone()}, nil},
+		{"subscribe option", nil, []WaitOption{WithWaitSubscribeOptions(WithCancelOnDone())}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			httpClient := &deadlineClient{}
+			srv, client := newAgentServer(t, func(a *agentServer, text string) {
+				a.userMessage(text)
+				a.content("working")
+			}, append(tt.sdkOpts, WithClient(httpClient))...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			started := time.Now()
+			res, err := client.Messages.SendMessageAndWait(ctx, "s1", operations.SendMessageRequestBody{Text: "hi"}, tt.waitOpts...)
+			var cancelErr *apierrors.CancelError
+			require.ErrorAs(t, err, &cancelErr)
+			assert.True(t, cancelErr.Acknowledged)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Equal(t, "working", res.Content)
+			assert.False(t, res.Done)
+			assert.Contains(t, srv.received(), "cancel")
+
+			// The cancel request outlives ctx, bounded by its own timeout.
+			httpClient.mu.Lock()
+			defer httpClient.mu.Unlock()
+			require.NotEmpty(t, httpClient.deadlines)
+			assert.WithinRange(t, httpClient.deadlines[0], started.Add(cancelTimeout), time.Now().Add(cancelTimeout))
+		})
+	}
+}
+
+func TestSubscription_CancelOnDone(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		sdkOpts []SDKOption
+		subOpts []SubscribeOption
+	}{
+		{"SDK option", []SDKOption{WithCancelOnContextDone()}, nil},
+		{"subscribe option", nil, []SubscribeOption{WithCancelOnDone()}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, client := newAgentServer(t, nil, tt.sdkOpts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sub, err := client.Streaming.Subscribe(ctx, "s1", tt.subOpts...)
+			require.NoError(t, err)
+			defer sub.Close()
+
+			cancel()
+			assert.False(t, sub.Next())
+			var cancelErr *apierrors.CancelError
+			require.ErrorAs(t, sub.Err(), &cancelErr)
+			assert.Equal(t, "s1", cancelErr.SessionID)
+			assert.True(t, cancelErr.Acknowledged)
+			assert.ErrorIs(t, sub.Err(), context.Canceled)
+			assert.Equal(t, []string{"cancel"}, srv.received())
+		})
+	}
+}
+
+func TestSubscription_CloseDoesNotCancel(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, nil, WithCancelOnContextDone())
+
+	sub, err := client.Streaming.Subscribe(context.Background(), "s1")
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+	assert.False(t, sub.Next())
+	assert.Empty(t, srv.received())
+}
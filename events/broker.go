@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// Filter selects the events delivered to a broker subscriber.
+type Filter func(event *components.SSEEventStream) bool
+
+// FilterTypes selects events of the given types.
+func FilterTypes(types ...components.SSEEventStreamType) Filter {
+	set := make(map[components.SSEEventStreamType]struct{}, len(types))
+	for _, t := range types {
+		set[t] = struct{}{}
+	}
+	return func(event *components.SSEEventStream) bool {
+		_, ok := set[event.Type]
+		return ok
+	}
+}
+
+// FilterAgent selects events from one agent: the root agent when
+// parentToolCallID is nil, otherwise the subagent spawned by that tool call.
+func FilterAgent(parentToolCallID *string) Filter {
+	return func(event *components.SSEEventStream) bool {
+		return sameString(ParentToolCallID(event), parentToolCallID)
+	}
+}
+
+type subscriberOptions struct {
+	filters []Filter
+	replay  bool
+}
+
+// SubscriberOption configures a broker subscriber.
+type SubscriberOption func(*subscriberOptions)
+
+// WithFilter restricts a subscriber to events accepted by every given filter.
+func WithFilter(filters ...Filter) SubscriberOption {
+	return func(o *subscriberOptions) {
+		o.filters = append(o.filters, filters...)
+	}
+}
+
+// WithReplay delivers the events of the current root turn received before the
+// subscriber joined, ahead of live events.
+func WithReplay() SubscriberOption {
+	return func(o *subscriberOptions) {
+		o.replay = true
+	}
+}
+
+// Subscriber receives events from a Broker.
+type Subscriber struct {
+	broker  *Broker
+	filters []Filter
+	events  *mailbox[*components.SSEEventStream]
+}
+
+// Events returns the subscriber's events. The channel is closed on Unsubscribe,
+// or once the buffered events are received when the broker stops. Events are
+// buffered without bound, so a slow subscriber never holds up the others; one
+// that stops reading must Unsubscribe to release them.
+func (s *Subscriber) Events() <-chan *components.SSEEventStream {
+	return s.events.channel()
+}
+
+// Unsubscribe stops delivery to the subscriber, drops the events it has not
+// received yet and closes its channel.
+func (s *Subscriber) Unsubscribe() {
+	s.broker.mu.Lock()
+	delete(s.broker.subscribers, s)
+	s.broker.mu.Unlock()
+	s.events.discard()
+}
+
+func (s *Subscriber) accepts(event *components.SSEEventStream) bool {
+	for _, f := range s.filters {
+		if !f(event) {
+			return false
+		}
+	}
+	return true
+}
+
+// Broker fans the events of one upstream session stream out to any number of
+// in-process subscribers, so several consumers can share a single connection
+// and observe the same ordering.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	turn        []*components.SSEEventStream
+	turnDone    bool
+	stopped     bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[*Subscriber]struct{}{}}
+}
+
+// Subscribe registers a subscriber. Subscribing after the broker has stopped
+// returns a subscriber whose channel is already closed.
+func (b *Broker) Subscribe(opts ...SubscriberOption) *Subscriber {
+	var o subscriberOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Subscriber{
+		broker:  b,
+		filters: o.filters,
+		events:  newMailbox[*components.SSEEventStream](),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if o.replay {
+		for _, event := range b.turn {
+			if s.accepts(event) {
+				s.events.push(event)
+			}
+		}
+	}
+	if b.stopped {
+		s.events.close()
+		return s
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Run reads events from src and publishes them until the stream ends or ctx is
+// done, then closes every subscriber.
+func (b *Broker) Run(ctx context.Context, src Source) error {
+	defer b.stop()
+
+	return Each(ctx, src, func(event *components.SSEEventStream) error {
+		b.Publish(event)
+		return nil
+	})
+}
+
+// Publish delivers an event to every subscriber whose filters accept it.
+func (b *Broker) Publish(event *components.SSEEventStream) {
+	if event == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record(event)
+	for s := range b.subscribers {
+		if s.accepts(event) {
+			s.events.push(event)
+		}
+	}
+}
+
+// record keeps the events of the current root turn for late joiners. A turn
+// starts with the user message or the first event after the previous turn
+// completed; connection bookkeeping is not kept.
+func (b *Broker) record(event *components.SSEEventStream) {
+	switch event.Type {
+	case components.SSEEventStreamTypeConnected, components.SSEEventStreamTypeHeartbeat,
+		components.SSEEventStreamTypeSessionCreated, components.SSEEventStreamTypeSessionDeleted:
+		return
+	}
+
+	if b.turnDone || (event.SSEUserMessageCreatedEvent != nil && ParentToolCallID(event) == nil) {
+		b.turn = nil
+		b.turnDone = false
+	}
+	b.turn = append(b.turn, event)
+	b.turnDone = IsTurnComplete(event)
+}
+
+func (b *Broker) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+	for s := range b.subscribers {
+		s.events.close()
+	}
+	b.subscribers = map[*Subscriber]struct{}{}
+}
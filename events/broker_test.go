@@ -0,0 +1,50 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_FanOutAndReplay(t *testing.T) {
+	t.Parallel()
+	content := func(text string, parent *string) *components.SSEEventStream {
+		e := components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: text, ParentToolCallID: parent}})
+		return &e
+	}
+	user := components.CreateSSEEventStreamUserMessageCreated(components.SSEUserMessageCreatedEvent{Data: components.SSEUserMessageCreatedEventData{MessageID: "u1", Content: "hi"}})
+	heartbeat := components.CreateSSEEventStreamHeartbeat(components.SSEHeartbeatEvent{})
+
+	b := NewBroker()
+	all := b.Subscribe()
+	root := b.Subscribe(WithFilter(FilterTypes(components.SSEEventStreamTypeContent), FilterAgent(nil)))
+	dropped := b.Subscribe()
+
+	b.Publish(&heartbeat)
+	b.Publish(&user)
+	b.Publish(content("a", nil))
+	dropped.Unsubscribe()
+	b.Publish(content("sub", ptr("task1")))
+
+	late := b.Subscribe(WithReplay(), WithFilter(FilterTypes(components.SSEEventStreamTypeContent)))
+	b.Publish(content("b", nil))
+	b.stop()
+
+	assert.Len(t, drainAll(all.Events()), 5)
+	assert.Equal(t, []string{"a", "b"}, drain(root.Events()))
+	assert.Equal(t, []string{"a", "sub", "b"}, drain(late.Events()))
+	assert.Empty(t, drainAll(dropped.Events()), "Unsubscribe drops undelivered events")
+
+	// Subscribing after the broker stopped yields a closed channel.
+	_, open := <-b.Subscribe().Events()
+	assert.False(t, open)
+}
+
+func drainAll(ch <-chan *components.SSEEventStream) []*components.SSEEventStream {
+	var out []*components.SSEEventStream
+	for event := range ch {
+		out = append(out, event)
+	}
+	return out
+}
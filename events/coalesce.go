@@ -0,0 +1,37 @@
+package events
+
+import "github.com/recreate-run/mix-go-sdk/models/components"
+
+// CoalesceDeltas merges consecutive content or thinking deltas from the same
+// agent and assistant message into one event carrying the later event's ID.
+// It is meant for the OverflowCoalesce policy of EventStream.Events:
+//
+//	ch := es.Events(stream.WithCoalesce(events.CoalesceDeltas))
+func CoalesceDeltas(last, next components.SSEEventStream) (components.SSEEventStream, bool) {
+	switch {
+	case last.SSEContentEvent != nil && next.SSEContentEvent != nil:
+		a, b := last.SSEContentEvent, next.SSEContentEvent
+		if !sameString(a.Data.ParentToolCallID, b.Data.ParentToolCallID) || !sameString(a.Data.AssistantMessageID, b.Data.AssistantMessageID) {
+			return last, false
+		}
+		merged := *b
+		merged.Data.Content = a.Data.Content + b.Data.Content
+		return components.CreateSSEEventStreamContent(merged), true
+	case last.SSEThinkingEvent != nil && next.SSEThinkingEvent != nil:
+		a, b := last.SSEThinkingEvent, next.SSEThinkingEvent
+		if !sameString(a.Data.ParentToolCallID, b.Data.ParentToolCallID) || !sameString(a.Data.AssistantMessageID, b.Data.AssistantMessageID) {
+			return last, false
+		}
+		merged := *b
+		merged.Data.Content = a.Data.Content + b.Data.Content
+		return components.CreateSSEEventStreamThinking(merged), true
+	}
+	return last, false
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
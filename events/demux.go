@@ -0,0 +1,255 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// AgentEventKind identifies an agent lifecycle transition.
+type AgentEventKind string
+
+const (
+	AgentStarted   AgentEventKind = "started"
+	AgentCompleted AgentEventKind = "completed"
+)
+
+// AgentEvent signals that a subagent started or completed.
+type AgentEvent struct {
+	Kind  AgentEventKind
+	Agent *Agent
+}
+
+// Agent is a node in the agent tree of a session: the root agent, or a
+// subagent spawned by a Task tool call.
+type Agent struct {
+	// ID of the Task tool call that spawned the subagent; empty for the root
+	// agent. It matches SessionData.ParentToolCallID of the subagent session.
+	ID string
+	// Parent agent; nil for the root agent
+	Parent *Agent
+	// Nesting depth; zero for the root agent
+	Depth int
+
+	events *mailbox[*components.SSEEventStream]
+
+	mu       sync.Mutex
+	children []*Agent
+	input    string
+	done     bool
+}
+
+// Events returns the agent's own events, excluding those of its subagents.
+// The channel is closed once the buffered events are received after the agent
+// completes or the demultiplexer stops. Events are buffered without bound, so
+// a slow reader never stalls others. Delivery starts with the first call, so
+// agents whose events are never requested hold no goroutine; once Events was
+// called, read the channel to the end or call Discard.
+func (a *Agent) Events() <-chan *components.SSEEventStream {
+	return a.events.channel()
+}
+
+// Discard drops the agent's buffered events, and those it receives later, and
+// closes its channel.
+func (a *Agent) Discard() {
+	a.events.discard()
+}
+
+// Children returns the subagents spawned by this agent so far.
+func (a *Agent) Children() []*Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*Agent(nil), a.children...)
+}
+
+// Input returns the JSON input of the Task tool call that spawned the
+// subagent, once it has finished streaming.
+func (a *Agent) Input() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.input
+}
+
+// Done reports whether the agent has completed.
+func (a *Agent) Done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done
+}
+
+// Demux splits a session stream into per-agent event streams using the
+// ParentToolCallID carried by each event. Events carry no usage; the cost of
+// each agent is reported by Sessions.AgentCosts in the mix package.
+type Demux struct {
+	root      *Agent
+	lifecycle *mailbox[AgentEvent]
+
+	mu     sync.Mutex
+	agents map[string]*Agent
+	tasks  map[string]struct{}
+}
+
+func NewDemux() *Demux {
+	return &Demux{
+		root:      &Agent{events: newMailbox[*components.SSEEventStream]()},
+		lifecycle: newMailbox[AgentEvent](),
+		agents:    map[string]*Agent{},
+		tasks:     map[string]struct{}{},
+	}
+}
+
+// Root returns the root agent.
+func (d *Demux) Root() *Agent {
+	return d.root
+}
+
+// Agent returns the subagent spawned by the given tool call.
+func (d *Demux) Agent(toolCallID string) (*Agent, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a, ok := d.agents[toolCallID]
+	return a, ok
+}
+
+// Lifecycle returns a channel of subagent start and completion signals. It is
+// closed when Run returns.
+func (d *Demux) Lifecycle() <-chan AgentEvent {
+	return d.lifecycle.channel()
+}
+
+// Run routes events from src until the stream ends or ctx is done, then closes
+// every agent's channel and the lifecycle channel. When ctx is done the events
+// not received yet are dropped, as by Discard.
+func (d *Demux) Run(ctx context.Context, src Source) error {
+	defer func() {
+		if ctx.Err() != nil {
+			d.Discard()
+			return
+		}
+		d.close()
+	}()
+
+	return Each(ctx, src, func(event *components.SSEEventStream) error {
+		d.Route(event)
+		return nil
+	})
+}
+
+// Route delivers a single event to the agent it belongs to. Events that are
+// not tied to an agent, such as heartbeats, go to the root agent.
+func (d *Demux) Route(event *components.SSEEventStream) {
+	if event == nil {
+		return
+	}
+
+	d.mu.Lock()
+	owner := d.root
+	if parent := ParentToolCallID(event); parent != nil {
+		owner = d.agent(*parent, d.root)
+	}
+
+	// A Task tool call announces the subagent it is about to spawn.
+	if start := event.SSEToolUseStartEvent; start != nil && isTask(start.Data.Name) {
+		d.tasks[start.Data.ID] = struct{}{}
+		d.agent(start.Data.ID, owner)
+	}
+	if complete := event.SSEToolUseParameterStreamingCompleteEvent; complete != nil && isTask(complete.Data.Name) {
+		child := d.agent(complete.Data.ID, owner)
+		child.mu.Lock()
+		child.input = complete.Data.Input
+		child.mu.Unlock()
+	}
+	d.mu.Unlock()
+
+	owner.events.push(event)
+
+	// The subagent ends when its Task tool call finishes in the parent, or on
+	// its own complete event when the Task call was never observed.
+	switch {
+	case event.SSEToolExecutionCompleteEvent != nil:
+		id := event.SSEToolExecutionCompleteEvent.Data.ToolCallID
+		d.mu.Lock()
+		child, ok := d.agents[id]
+		d.mu.Unlock()
+		if ok {
+			d.complete(child)
+		}
+	case event.SSECompleteEvent != nil && owner != d.root:
+		d.mu.Lock()
+		_, announced := d.tasks[owner.ID]
+		d.mu.Unlock()
+		if !announced {
+			d.complete(owner)
+		}
+	}
+}
+
+// agent returns the subagent for a tool call, creating it under parent if it
+// has not been seen yet. d.mu must be held.
+func (d *Demux) agent(id string, parent *Agent) *Agent {
+	if a, ok := d.agents[id]; ok {
+		return a
+	}
+
+	a := &Agent{
+		ID:     id,
+		Parent: parent,
+		Depth:  parent.Depth + 1,
+		events: newMailbox[*components.SSEEventStream](),
+	}
+	d.agents[id] = a
+
+	parent.mu.Lock()
+	parent.children = append(parent.children, a)
+	parent.mu.Unlock()
+
+	d.lifecycle.push(AgentEvent{Kind: AgentStarted, Agent: a})
+	return a
+}
+
+func (d *Demux) complete(a *Agent) {
+	a.mu.Lock()
+	if a.done {
+		a.mu.Unlock()
+		return
+	}
+	a.done = true
+	a.mu.Unlock()
+
+	a.events.close()
+	d.lifecycle.push(AgentEvent{Kind: AgentCompleted, Agent: a})
+}
+
+// Discard drops the buffered events of every agent and the lifecycle
+// signals, and closes their channels. Use it when the demultiplexed streams are
+// no longer read.
+func (d *Demux) Discard() {
+	for _, a := range d.all() {
+		a.events.discard()
+	}
+	d.lifecycle.discard()
+}
+
+func (d *Demux) close() {
+	for _, a := range d.all() {
+		a.events.close()
+	}
+	d.lifecycle.close()
+}
+
+// all returns the root agent and every subagent seen so far.
+func (d *Demux) all() []*Agent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	agents := make([]*Agent, 0, len(d.agents)+1)
+	agents = append(agents, d.root)
+	for _, a := range d.agents {
+		agents = append(agents, a)
+	}
+	return agents
+}
+
+func isTask(name components.ToolName) bool {
+	return name.CoreToolName != nil && *name.CoreToolName == components.CoreToolNameTask
+}
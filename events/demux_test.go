@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceSource struct {
+	events []components.SSEEventStream
+	pos    int
+}
+
+func (s *sliceSource) Next() bool {
+	if s.pos >= len(s.events) {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+func (s *sliceSource) Value() *components.SSEEventStream {
+	return &s.events[s.pos-1]
+}
+
+func (s *sliceSource) Err() error {
+	return nil
+}
+
+func drain(ch <-chan *components.SSEEventStream) []string {
+	var out []string
+	for event := range ch {
+		if event.SSEContentEvent != nil {
+			out = append(out, event.SSEContentEvent.Data.Content)
+		}
+	}
+	return out
+}
+
+func TestDemux_SplitsSubagents(t *testing.T) {
+	t.Parallel()
+	task := components.CreateToolNameCoreToolName(components.CoreToolNameTask)
+	content := func(text string, parent *string) components.SSEEventStream {
+		return components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: text, ParentToolCallID: parent}})
+	}
+
+	src := &sliceSource{events: []components.SSEEventStream{
+		content("root-1", nil),
+		components.CreateSSEEventStreamToolUseStart(components.SSEToolUseStartEvent{Data: components.SSEToolUseStartEventData{ID: "task1", Name: task}}),
+		components.CreateSSEEventStreamToolUseParameterStreamingComplete(components.SSEToolUseParameterStreamingCompleteEvent{Data: components.SSEToolUseParameterStreamingCompleteEventData{ID: "task1", Name: task, Input: `{"prompt":"look"}`}}),
+		content("child-1", ptr("task1")),
+		components.CreateSSEEventStreamToolUseStart(components.SSEToolUseStartEvent{Data: components.SSEToolUseStartEventData{ID: "task2", Name: task, ParentToolCallID: ptr("task1")}}),
+		content("grandchild-1", ptr("task2")),
+		components.CreateSSEEventStreamToolExecutionComplete(components.SSEToolExecutionCompleteEvent{Data: components.SSEToolExecutionCompleteEventData{ToolCallID: "task2", ToolName: task, Success: true, ParentToolCallID: ptr("task1")}}),
+		content("child-2", ptr("task1")),
+		components.CreateSSEEventStreamToolExecutionComplete(components.SSEToolExecutionCompleteEvent{Data: components.SSEToolExecutionCompleteEventData{ToolCallID: "task1", ToolName: task, Success: true}}),
+		content("root-2", nil),
+	}}
+
+	d := NewDemux()
+	require.NoError(t, d.Run(context.Background(), src))
+
+	child, ok := d.Agent("task1")
+	require.True(t, ok)
+	grandchild, ok := d.Agent("task2")
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"root-1", "root-2"}, drain(d.Root().Events()))
+	assert.Equal(t, []string{"child-1", "child-2"}, drain(child.Events()))
+	assert.Equal(t, []string{"grandchild-1"}, drain(grandchild.Events()))
+
+	assert.Equal(t, `{"prompt":"look"}`, child.Input())
+	assert.Same(t, child, grandchild.Parent)
+	assert.Equal(t, 2, grandchild.Depth)
+	assert.Equal(t, []*Agent{child}, d.Root().Children())
+	assert.True(t, child.Done())
+
+	var lifecycle []string
+	for ev := range d.Lifecycle() {
+		lifecycle = append(lifecycle, string(ev.Kind)+":"+ev.Agent.ID)
+	}
+	assert.Equal(t, []string{"started:task1", "started:task2", "completed:task2", "completed:task1"}, lifecycle)
+}
+
+func TestDemux_Discard(t *testing.T) {
+	t.Parallel()
+	content := func(text string, parent *string) components.SSEEventStream {
+		return components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: text, ParentToolCallID: parent}})
+	}
+
+	d := NewDemux()
+	d.Route(ptr(content("child-1", ptr("task1"))))
+	child, ok := d.Agent("task1")
+	require.True(t, ok)
+	child.Discard()
+	d.Route(ptr(content("child-2", ptr("task1"))))
+	assert.Empty(t, drain(child.Events()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.Route(ptr(content("root-1", nil)))
+	assert.ErrorIs(t, d.Run(ctx, &sliceSource{}), context.Canceled)
+	assert.Empty(t, drain(d.Root().Events()), "a cancelled Run drops unread events")
+	_, open := <-d.Lifecycle()
+	assert.False(t, open)
+}
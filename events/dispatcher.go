@@ -0,0 +1,253 @@
+// Package events provides helpers for consuming the session event stream
+// returned by Streaming.StreamEvents and Streaming.Subscribe.
+package events
+
+import (
+	"context"
+	"errors"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// Source is a stream of session events. It is satisfied by
+// *stream.EventStream[components.SSEEventStream] and *mix.Subscription.
+type Source interface {
+	Next() bool
+	Value() *components.SSEEventStream
+	Err() error
+}
+
+// ErrStop may be returned by a handler to end Dispatcher.Run, or by the
+// function passed to Each, to stop without error.
+var ErrStop = errors.New("events: stop dispatching")
+
+// Each calls fn with every event from src until the stream ends, ctx is done,
+// or fn returns an error. It returns ctx's error once ctx is done, nil when fn
+// returns ErrStop, fn's other errors, and otherwise the error that ended the
+// stream, which is nil when it ended cleanly.
+func Each(ctx context.Context, src Source, fn func(event *components.SSEEventStream) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !src.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return src.Err()
+		}
+		if err := fn(src.Value()); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Dispatcher routes session events to typed handlers. Handlers returning an
+// error stop Run, which reports that error (or nil for ErrStop).
+type Dispatcher struct {
+	onConnected                         func(components.SSEConnectedEventData) error
+	onHeartbeat                         func(components.SSEHeartbeatEventData) error
+	onError                             func(components.SSEErrorEventData) error
+	onComplete                          func(components.SSECompleteEventData) error
+	onThinking                          func(components.SSEThinkingEventData) error
+	onContent                           func(components.SSEContentEventData) error
+	onToolUseStart                      func(components.SSEToolUseStartEventData) error
+	onToolUseParameterStreamingComplete func(components.SSEToolUseParameterStreamingCompleteEventData) error
+	onToolUseParameterDelta             func(components.SSEToolUseParameterDeltaEventData) error
+	onToolExecutionStart                func(components.SSEToolExecutionStartEventData) error
+	onToolExecutionComplete             func(components.SSEToolExecutionCompleteEventData) error
+	onPermission                        func(components.SSEPermissionEventData) error
+	onNotification                      func(components.SSENotificationEventData) error
+	onUserMessageCreated                func(components.SSEUserMessageCreatedEventData) error
+	onSessionCreated                    func(components.SSESessionCreatedEventData) error
+	onSessionDeleted                    func(components.SSESessionDeletedEventData) error
+	onEvent                             func(*components.SSEEventStream) error
+}
+
+// NewDispatcher creates a Dispatcher with no handlers registered.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// OnConnected registers the handler for connected events, sent once the
+// stream is open.
+func (d *Dispatcher) OnConnected(fn func(components.SSEConnectedEventData) error) {
+	d.onConnected = fn
+}
+
+// OnHeartbeat registers the handler for heartbeat events.
+func (d *Dispatcher) OnHeartbeat(fn func(components.SSEHeartbeatEventData) error) {
+	d.onHeartbeat = fn
+}
+
+// OnError registers the handler for error events.
+func (d *Dispatcher) OnError(fn func(components.SSEErrorEventData) error) {
+	d.onError = fn
+}
+
+// OnComplete registers the handler for complete events, from the root agent
+// and subagents alike.
+func (d *Dispatcher) OnComplete(fn func(components.SSECompleteEventData) error) {
+	d.onComplete = fn
+}
+
+// OnThinking registers the handler for thinking deltas.
+func (d *Dispatcher) OnThinking(fn func(components.SSEThinkingEventData) error) {
+	d.onThinking = fn
+}
+
+// OnContent registers the handler for content deltas.
+func (d *Dispatcher) OnContent(fn func(components.SSEContentEventData) error) {
+	d.onContent = fn
+}
+
+// OnToolUseStart registers the handler for the start of a tool call, before
+// its parameters are streamed.
+func (d *Dispatcher) OnToolUseStart(fn func(components.SSEToolUseStartEventData) error) {
+	d.onToolUseStart = fn
+}
+
+// OnToolUseParameterStreamingComplete registers the handler for the end of a
+// tool call's parameter stream.
+func (d *Dispatcher) OnToolUseParameterStreamingComplete(fn func(components.SSEToolUseParameterStreamingCompleteEventData) error) {
+	d.onToolUseParameterStreamingComplete = fn
+}
+
+// OnToolUseParameterDelta registers the handler for tool call parameter
+// deltas.
+func (d *Dispatcher) OnToolUseParameterDelta(fn func(components.SSEToolUseParameterDeltaEventData) error) {
+	d.onToolUseParameterDelta = fn
+}
+
+// OnToolExecutionStart registers the handler for the start of a tool
+// execution.
+func (d *Dispatcher) OnToolExecutionStart(fn func(components.SSEToolExecutionStartEventData) error) {
+	d.onToolExecutionStart = fn
+}
+
+// OnToolExecutionComplete registers the handler for the end of a tool
+// execution.
+func (d *Dispatcher) OnToolExecutionComplete(fn func(components.SSEToolExecutionCompleteEventData) error) {
+	d.onToolExecutionComplete = fn
+}
+
+// OnPermission registers the handler for permission requests.
+func (d *Dispatcher) OnPermission(fn func(components.SSEPermissionEventData) error) {
+	d.onPermission = fn
+}
+
+// OnNotification registers the handler for notifications.
+func (d *Dispatcher) OnNotification(fn func(components.SSENotificationEventData) error) {
+	d.onNotification = fn
+}
+
+// OnUserMessageCreated registers the handler for user messages recorded by the
+// server.
+func (d *Dispatcher) OnUserMessageCreated(fn func(components.SSEUserMessageCreatedEventData) error) {
+	d.onUserMessageCreated = fn
+}
+
+// OnSessionCreated registers the handler for session created events.
+func (d *Dispatcher) OnSessionCreated(fn func(components.SSESessionCreatedEventData) error) {
+	d.onSessionCreated = fn
+}
+
+// OnSessionDeleted registers the handler for session deleted events.
+func (d *Dispatcher) OnSessionDeleted(fn func(components.SSESessionDeletedEventData) error) {
+	d.onSessionDeleted = fn
+}
+
+// OnEvent registers a catch-all handler for events that have no typed
+// handler registered.
+func (d *Dispatcher) OnEvent(fn func(*components.SSEEventStream) error) {
+	d.onEvent = fn
+}
+
+// Dispatch delivers a single event to its handler.
+func (d *Dispatcher) Dispatch(event *components.SSEEventStream) error {
+	if event == nil {
+		return nil
+	}
+
+	var err error
+	handled := true
+	switch {
+	case event.SSEConnectedEvent != nil && d.onConnected != nil:
+		err = d.onConnected(event.SSEConnectedEvent.Data)
+	case event.SSEHeartbeatEvent != nil && d.onHeartbeat != nil:
+		err = d.onHeartbeat(event.SSEHeartbeatEvent.Data)
+	case event.SSEErrorEvent != nil && d.onError != nil:
+		err = d.onError(event.SSEErrorEvent.Data)
+	case event.SSECompleteEvent != nil && d.onComplete != nil:
+		err = d.onComplete(event.SSECompleteEvent.Data)
+	case event.SSEThinkingEvent != nil && d.onThinking != nil:
+		err = d.onThinking(event.SSEThinkingEvent.Data)
+	case event.SSEContentEvent != nil && d.onContent != nil:
+		err = d.onContent(event.SSEContentEvent.Data)
+	case event.SSEToolUseStartEvent != nil && d.onToolUseStart != nil:
+		err = d.onToolUseStart(event.SSEToolUseStartEvent.Data)
+	case event.SSEToolUseParameterStreamingCompleteEvent != nil && d.onToolUseParameterStreamingComplete != nil:
+		err = d.onToolUseParameterStreamingComplete(event.SSEToolUseParameterStreamingCompleteEvent.Data)
+	case event.SSEToolUseParameterDeltaEvent != nil && d.onToolUseParameterDelta != nil:
+		err = d.onToolUseParameterDelta(event.SSEToolUseParameterDeltaEvent.Data)
+	case event.SSEToolExecutionStartEvent != nil && d.onToolExecutionStart != nil:
+		err = d.onToolExecutionStart(event.SSEToolExecutionStartEvent.Data)
+	case event.SSEToolExecutionCompleteEvent != nil && d.onToolExecutionComplete != nil:
+		err = d.onToolExecutionComplete(event.SSEToolExecutionCompleteEvent.Data)
+	case event.SSEPermissionEvent != nil && d.onPermission != nil:
+		err = d.onPermission(event.SSEPermissionEvent.Data)
+	case event.SSENotificationEvent != nil && d.onNotification != nil:
+		err = d.onNotification(event.SSENotificationEvent.Data)
+	case event.SSEUserMessageCreatedEvent != nil && d.onUserMessageCreated != nil:
+		err = d.onUserMessageCreated(event.SSEUserMessageCreatedEvent.Data)
+	case event.SSESessionCreatedEvent != nil && d.onSessionCreated != nil:
+		err = d.onSessionCreated(event.SSESessionCreatedEvent.Data)
+	case event.SSESessionDeletedEvent != nil && d.onSessionDeleted != nil:
+		err = d.onSessionDeleted(event.SSESessionDeletedEvent.Data)
+	default:
+		handled = false
+	}
+
+	if !handled && d.onEvent != nil {
+		err = d.onEvent(event)
+	}
+
+	return err
+}
+
+// Run dispatches events from src until the root agent's complete event has
+// been handled, a handler returns an error, ctx is done, or the stream ends.
+// The source should be opened with ctx so that cancellation also interrupts a
+// blocked read. Subagent complete events do not end Run.
+func (d *Dispatcher) Run(ctx context.Context, src Source) error {
+	return Each(ctx, src, func(event *components.SSEEventStream) error {
+		if err := d.Dispatch(event); err != nil {
+			return err
+		}
+		if IsTurnComplete(event) {
+			return ErrStop
+		}
+		return nil
+	})
+}
+
+// ToolName returns the name of a tool: the core tool name, or the name of an
+// MCP tool, which is {server}_{tool}. It returns "" when name holds neither.
+func ToolName(name components.ToolName) string {
+	switch {
+	case name.CoreToolName != nil:
+		return string(*name.CoreToolName)
+	case name.Str != nil:
+		return *name.Str
+	}
+	return ""
+}
+
+// IsTurnComplete reports whether event is the complete event of the root
+// agent, as opposed to a nested subagent.
+func IsTurnComplete(event *components.SSEEventStream) bool {
+	return event != nil && event.SSECompleteEvent != nil && event.SSECompleteEvent.Data.ParentToolCallID == nil
+}
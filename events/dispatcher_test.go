@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+func contentEvent(text string) components.SSEEventStream {
+	return components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: text}})
+}
+
+func completeEvent(parent *string) components.SSEEventStream {
+	return components.CreateSSEEventStreamComplete(components.SSECompleteEvent{Data: components.SSECompleteEventData{Done: true, ParentToolCallID: parent}})
+}
+
+func TestDispatcher_Routing(t *testing.T) {
+	t.Parallel()
+	var got []string
+	d := NewDispatcher()
+	d.OnContent(func(data components.SSEContentEventData) error {
+		got = append(got, "content:"+data.Content)
+		return nil
+	})
+	d.OnPermission(func(data components.SSEPermissionEventData) error {
+		got = append(got, "permission:"+data.ID)
+		return nil
+	})
+	d.OnEvent(func(event *components.SSEEventStream) error {
+		got = append(got, "other:"+string(event.Type))
+		return nil
+	})
+
+	src := &sliceSource{events: []components.SSEEventStream{
+		contentEvent("hi"),
+		components.CreateSSEEventStreamThinking(components.SSEThinkingEvent{Data: components.SSEThinkingEventData{Content: "hmm"}}),
+		components.CreateSSEEventStreamPermission(components.SSEPermissionEvent{Data: components.SSEPermissionEventData{ID: "p1"}}),
+		completeEvent(nil),
+		contentEvent("after complete"),
+	}}
+	require.NoError(t, d.Run(context.Background(), src))
+	assert.Equal(t, []string{"content:hi", "other:thinking", "permission:p1", "other:complete"}, got)
+	assert.NoError(t, d.Dispatch(nil))
+}
+
+func TestDispatcher_SubagentCompleteDoesNotEndRun(t *testing.T) {
+	t.Parallel()
+	var completes int
+	d := NewDispatcher()
+	d.OnComplete(func(components.SSECompleteEventData) error {
+		completes++
+		return nil
+	})
+
+	src := &sliceSource{events: []components.SSEEventStream{
+		completeEvent(ptr("task1")),
+		completeEvent(nil),
+		completeEvent(nil),
+	}}
+	require.NoError(t, d.Run(context.Background(), src))
+	assert.Equal(t, 2, completes)
+	assert.Equal(t, 2, src.pos, "Run stops at the root complete")
+}
+
+func TestDispatcher_Stop(t *testing.T) {
+	t.Parallel()
+	boom := errors.New("boom")
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"ErrStop", ErrStop, nil},
+		{"wrapped ErrStop", errors.Join(errors.New("done"), ErrStop), nil},
+		{"handler error", boom, boom},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var seen int
+			d := NewDispatcher()
+			d.OnContent(func(components.SSEContentEventData) error {
+				seen++
+				return tt.err
+			})
+			src := &sliceSource{events: []components.SSEEventStream{contentEvent("a"), contentEvent("b")}}
+			assert.Equal(t, tt.wantErr, d.Run(context.Background(), src))
+			assert.Equal(t, 1, seen)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := NewDispatcher().Run(ctx, &sliceSource{events: []components.SSEEventStream{contentEvent("a")}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// failingSource ends with err after its events.
+type failingSource struct {
+	sliceSource
+	err error
+}
+
+func (s *failingSource) Err() error {
+	return s.err
+}
+
+func TestEach(t *testing.T) {
+	t.Parallel()
+	boom := errors.New("boom")
+	broken := errors.New("connection reset")
+	tests := []struct {
+		name     string
+		src      Source
+		fnErr    error
+		wantSeen int
+		wantErr  error
+	}{
+		{"stream ends", &sliceSource{events: []components.SSEEventStream{contentEvent("a"), contentEvent("b")}}, nil, 2, nil},
+		{"stream fails", &failingSource{sliceSource{events: []components.SSEEventStream{contentEvent("a")}}, broken}, nil, 1, broken},
+		{"ErrStop", &sliceSource{events: []components.SSEEventStream{contentEvent("a"), contentEvent("b")}}, ErrStop, 1, nil},
+		{"fn error", &failingSource{sliceSource{events: []components.SSEEventStream{contentEvent("a"), contentEvent("b")}}, broken}, boom, 1, boom},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var seen int
+			err := Each(context.Background(), tt.src, func(*components.SSEEventStream) error {
+				seen++
+				return tt.fnErr
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantSeen, seen)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var seen int
+	err := Each(ctx, &sliceSource{events: []components.SSEEventStream{contentEvent("a"), contentEvent("b")}}, func(*components.SSEEventStream) error {
+		seen++
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, seen)
+}
+
+func TestToolName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "Bash", ToolName(components.CreateToolNameCoreToolName(components.CoreToolNameBash)))
+	assert.Equal(t, "github_create_issue", ToolName(components.CreateToolNameStr("github_create_issue")))
+	assert.Equal(t, "", ToolName(components.ToolName{}))
+}
@@ -0,0 +1,95 @@
+package events
+
+import "sync"
+
+// mailbox is an unbounded queue drained into a channel by its own goroutine,
+// so producers never block on slow consumers. The goroutine starts when the
+// channel is first requested, so that a mailbox nobody reads holds no
+// goroutine and is freed with its queue.
+type mailbox[T any] struct {
+	out   chan T
+	done  chan struct{}
+	start sync.Once
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []T
+	closed    bool
+	discarded bool
+}
+
+func newMailbox[T any]() *mailbox[T] {
+	m := &mailbox[T]{out: make(chan T), done: make(chan struct{})}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// channel returns the channel the queue is drained into.
+func (m *mailbox[T]) channel() <-chan T {
+	m.start.Do(func() { go m.pump() })
+	return m.out
+}
+
+func (m *mailbox[T]) push(v T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.queue = append(m.queue, v)
+	m.cond.Signal()
+}
+
+// close stops accepting values and closes the channel once the queued ones
+// have been received.
+func (m *mailbox[T]) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.cond.Signal()
+}
+
+// discard drops the queued values and closes the channel without waiting for
+// a receiver, so that nothing is kept for a consumer that has gone away.
+func (m *mailbox[T]) discard() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.discarded {
+		return
+	}
+	m.closed = true
+	m.discarded = true
+	m.queue = nil
+	close(m.done)
+	m.cond.Signal()
+}
+
+func (m *mailbox[T]) pump() {
+	defer close(m.out)
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 && !m.closed {
+			m.cond.Wait()
+		}
+		if len(m.queue) == 0 {
+			m.mu.Unlock()
+			return
+		}
+		v := m.queue[0]
+		var zero T
+		m.queue[0] = zero
+		m.queue = m.queue[1:]
+		m.mu.Unlock()
+
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+		select {
+		case m.out <- v:
+		case <-m.done:
+			return
+		}
+	}
+}
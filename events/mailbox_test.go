@@ -0,0 +1,56 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailbox_CloseDeliversQueued(t *testing.T) {
+	t.Parallel()
+	m := newMailbox[int]()
+	for i := 1; i <= 3; i++ {
+		m.push(i)
+	}
+	m.close()
+	m.push(4)
+
+	var got []int
+	for v := range m.channel() {
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{1, 2, 3}, got)
+}
+
+func TestMailbox_DiscardWithoutReceiver(t *testing.T) {
+	t.Parallel()
+	m := newMailbox[int]()
+	for i := 1; i <= 3; i++ {
+		m.push(i)
+	}
+	// Let the pump block sending the first value to nobody.
+	out := m.channel()
+	time.Sleep(10 * time.Millisecond)
+	m.discard()
+	m.discard()
+	m.push(4)
+
+	select {
+	case _, open := <-out:
+		require.False(t, open, "discard drops queued values")
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after discard")
+	}
+}
+
+func TestMailbox_DiscardBeforeReceive(t *testing.T) {
+	t.Parallel()
+	m := newMailbox[int]()
+	m.push(1)
+	m.discard()
+
+	_, open := <-m.channel()
+	assert.False(t, open)
+}
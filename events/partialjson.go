@@ -0,0 +1,334 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ParsePartialJSON decodes a possibly truncated JSON document into the same
+// Go values encoding/json produces for an interface{} target. Open strings,
+// arrays and objects are closed, partial literals are completed, and object
+// members whose key or value has not started yet are dropped. It returns an
+// error only for input that is malformed rather than merely incomplete, and
+// nil when no value has started.
+func ParsePartialJSON(s string) (any, error) {
+	p := partialParser{s: s}
+	p.skipSpace()
+	if p.eof() {
+		return nil, nil
+	}
+
+	v, _, ok, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected trailing data")
+	}
+
+	return v, nil
+}
+
+type partialParser struct {
+	s   string
+	pos int
+}
+
+func (p *partialParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *partialParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *partialParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid JSON at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// value parses the next value. complete reports whether the value was fully
+// present, and ok whether any usable value was produced.
+func (p *partialParser) value() (v any, complete, ok bool, err error) {
+	switch c := p.s[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		str, complete := p.string()
+		return str, complete, true, nil
+	case c == 't':
+		return p.literal("true", true)
+	case c == 'f':
+		return p.literal("false", false)
+	case c == 'n':
+		return p.literal("null", nil)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	default:
+		return nil, false, false, p.errorf("unexpected character %q", c)
+	}
+}
+
+func (p *partialParser) object() (any, bool, bool, error) {
+	m := map[string]any{}
+	p.pos++
+
+	for first := true; ; first = false {
+		p.skipSpace()
+		if p.eof() {
+			return m, false, true, nil
+		}
+		if p.s[p.pos] == '}' && first {
+			p.pos++
+			return m, true, true, nil
+		}
+
+		if p.s[p.pos] != '"' {
+			return nil, false, false, p.errorf("expected object key")
+		}
+		key, complete := p.string()
+		if !complete {
+			return m, false, true, nil
+		}
+
+		p.skipSpace()
+		if p.eof() {
+			return m, false, true, nil
+		}
+		if p.s[p.pos] != ':' {
+			return nil, false, false, p.errorf("expected ':' after object key")
+		}
+		p.pos++
+
+		p.skipSpace()
+		if p.eof() {
+			return m, false, true, nil
+		}
+		v, complete, ok, err := p.value()
+		if err != nil {
+			return nil, false, false, err
+		}
+		if ok {
+			m[key] = v
+		}
+		if !complete {
+			return m, false, true, nil
+		}
+
+		p.skipSpace()
+		if p.eof() {
+			return m, false, true, nil
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return m, true, true, nil
+		default:
+			return nil, false, false, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+func (p *partialParser) array() (any, bool, bool, error) {
+	a := []any{}
+	p.pos++
+
+	for first := true; ; first = false {
+		p.skipSpace()
+		if p.eof() {
+			return a, false, true, nil
+		}
+		if p.s[p.pos] == ']' && first {
+			p.pos++
+			return a, true, true, nil
+		}
+
+		v, complete, ok, err := p.value()
+		if err != nil {
+			return nil, false, false, err
+		}
+		if ok {
+			a = append(a, v)
+		}
+		if !complete {
+			return a, false, true, nil
+		}
+
+		p.skipSpace()
+		if p.eof() {
+			return a, false, true, nil
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return a, true, true, nil
+		default:
+			return nil, false, false, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// string parses a string starting at the opening quote. A truncated escape
+// sequence at the end of input is dropped.
+func (p *partialParser) string() (string, bool) {
+	p.pos++
+	var buf []byte
+	start := p.pos
+
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '"':
+			if buf == nil {
+				str := p.s[start:p.pos]
+				p.pos++
+				return str, true
+			}
+			buf = append(buf, p.s[start:p.pos]...)
+			p.pos++
+			return string(buf), true
+		case c == '\\':
+			buf = append(buf, p.s[start:p.pos]...)
+			r, n := unescape(p.s[p.pos:])
+			if n == 0 {
+				p.pos = len(p.s)
+				return string(buf), false
+			}
+			buf = utf8.AppendRune(buf, r)
+			p.pos += n
+			start = p.pos
+		default:
+			p.pos++
+		}
+	}
+
+	buf = append(buf, p.s[start:]...)
+	return string(buf), false
+}
+
+// unescape decodes the escape sequence at the start of s, returning the number
+// of bytes consumed or zero if the sequence is truncated.
+func unescape(s string) (rune, int) {
+	if len(s) < 2 {
+		return 0, 0
+	}
+	switch s[1] {
+	case 'b':
+		return '\b', 2
+	case 'f':
+		return '\f', 2
+	case 'n':
+		return '\n', 2
+	case 'r':
+		return '\r', 2
+	case 't':
+		return '\t', 2
+	case 'u':
+		if len(s) < 6 {
+			return 0, 0
+		}
+		r1, err := strconv.ParseUint(s[2:6], 16, 16)
+		if err != nil {
+			return utf8.RuneError, 6
+		}
+		r := rune(r1)
+		if utf16.IsSurrogate(r) {
+			if len(s) < 12 {
+				if len(s) >= 7 && s[6] != '\\' {
+					return utf8.RuneError, 6
+				}
+				return 0, 0
+			}
+			if s[6] == '\\' && s[7] == 'u' {
+				if r2, err := strconv.ParseUint(s[8:12], 16, 16); err == nil {
+					if dec := utf16.DecodeRune(r, rune(r2)); dec != utf8.RuneError {
+						return dec, 12
+					}
+				}
+			}
+			return utf8.RuneError, 6
+		}
+		return r, 6
+	default:
+		return rune(s[1]), 2
+	}
+}
+
+func (p *partialParser) literal(word string, v any) (any, bool, bool, error) {
+	n := 0
+	for n < len(word) && p.pos+n < len(p.s) && p.s[p.pos+n] == word[n] {
+		n++
+	}
+	if p.pos+n == len(p.s) && n < len(word) {
+		p.pos = len(p.s)
+		return v, false, true, nil
+	}
+	if n < len(word) {
+		return nil, false, false, p.errorf("invalid literal")
+	}
+	p.pos += n
+	return v, true, true, nil
+}
+
+var errNoNumber = errors.New("no number")
+
+func (p *partialParser) number() (any, bool, bool, error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	text := p.s[start:p.pos]
+	complete := p.pos < len(p.s)
+	if !complete {
+		// Trim a dangling sign, decimal point or exponent marker.
+		for len(text) > 0 {
+			last := text[len(text)-1]
+			if last >= '0' && last <= '9' {
+				break
+			}
+			text = text[:len(text)-1]
+		}
+	}
+
+	f, err := parseNumber(text)
+	if err != nil {
+		if !complete {
+			return nil, false, false, nil
+		}
+		return nil, false, false, p.errorf("invalid number %q", p.s[start:p.pos])
+	}
+	return f, complete, true, nil
+}
+
+func parseNumber(text string) (float64, error) {
+	if text == "" || text == "-" {
+		return 0, errNoNumber
+	}
+	return strconv.ParseFloat(text, 64)
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePartialJSON(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{"empty", "", nil},
+		{"open object", `{`, map[string]any{}},
+		{"partial key", `{"comm`, map[string]any{}},
+		{"key without value", `{"command": `, map[string]any{}},
+		{"partial string value", `{"command": "ls -l`, map[string]any{"command": "ls -l"}},
+		{"truncated escape", `{"content": "a\`, map[string]any{"content": "a"}},
+		{"truncated unicode escape", `{"content": "a\u00`, map[string]any{"content": "a"}},
+		{"escapes", `{"content": "line\n\"q\" é`, map[string]any{"content": "line\n\"q\" é"}},
+		{"partial literal", `{"force": tr`, map[string]any{"force": true}},
+		{"partial number", `{"limit": 12`, map[string]any{"limit": float64(12)}},
+		{"dangling decimal", `{"limit": 1.`, map[string]any{"limit": float64(1)}},
+		{"dangling sign", `{"offset": -`, map[string]any{}},
+		{"trailing comma", `{"a": 1,`, map[string]any{"a": float64(1)}},
+		{"nested", `{"edits": [{"old": "x", "new": "y`, map[string]any{"edits": []any{map[string]any{"old": "x", "new": "y"}}}},
+		{"complete", `{"a": [1, null, false], "b": {}}`, map[string]any{"a": []any{float64(1), nil, false}, "b": map[string]any{}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParsePartialJSON(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePartialJSON_Malformed(t *testing.T) {
+	t.Parallel()
+	for _, input := range []string{`{"a" 1}`, `[1 2]`, `{"a": trux}`, `{"a": 1}}`} {
+		_, err := ParsePartialJSON(input)
+		assert.Error(t, err, input)
+	}
+}
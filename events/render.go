@@ -0,0 +1,345 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// ReasoningMode controls how a Renderer shows thinking deltas.
+type ReasoningMode int
+
+const (
+	// ReasoningCollapsed shows a single line per reasoning block.
+	ReasoningCollapsed ReasoningMode = iota
+	// ReasoningExpanded streams the reasoning text, dimmed when styled.
+	ReasoningExpanded
+	// ReasoningHidden omits reasoning entirely.
+	ReasoningHidden
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+
+	// ansiClearLine returns the cursor to the start of the line and erases it.
+	ansiClearLine = "\r\x1b[2K"
+)
+
+// RendererOption configures a Renderer.
+type RendererOption func(*Renderer)
+
+// WithANSI enables ANSI styling and in-place updates of tool status lines.
+// Without it the renderer writes plain text suitable for logs and CI.
+func WithANSI(enabled bool) RendererOption {
+	return func(r *Renderer) {
+		r.ansi = enabled
+	}
+}
+
+// WithReasoning selects how reasoning is shown. The default is
+// ReasoningCollapsed.
+func WithReasoning(mode ReasoningMode) RendererOption {
+	return func(r *Renderer) {
+		r.reasoning = mode
+	}
+}
+
+// WithIndent sets the indentation used per subagent level. The default is two
+// spaces.
+func WithIndent(indent string) RendererOption {
+	return func(r *Renderer) {
+		r.indent = indent
+	}
+}
+
+type lineKind int
+
+const (
+	lineNone lineKind = iota
+	lineContent
+	lineReasoning
+	lineStatus
+)
+
+// Renderer writes live agent turns to an io.Writer: streamed assistant text,
+// reasoning, one status line per tool call, permission and notification
+// prompts, with subagent output indented under the Task call that spawned it.
+// It is safe for concurrent use.
+type Renderer struct {
+	w         io.Writer
+	ansi      bool
+	reasoning ReasoningMode
+	indent    string
+
+	mu     sync.Mutex
+	err    error
+	inputs *ToolInputParser
+	// depth of the agent that issued each tool call, so that a subagent
+	// spawned by a Task call can be placed one level below it
+	depths map[string]int
+
+	kind       lineKind
+	lineDepth  int
+	lineParent *string
+	// tool call whose status line is the current line, if any
+	statusCall string
+	thinking   map[string]time.Time
+}
+
+// NewRenderer creates a Renderer writing plain text to w.
+func NewRenderer(w io.Writer, opts ...RendererOption) *Renderer {
+	r := &Renderer{
+		w:        w,
+		indent:   "  ",
+		inputs:   NewToolInputParser(),
+		depths:   map[string]int{},
+		thinking: map[string]time.Time{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run renders events from src until the stream ends or ctx is done.
+func (r *Renderer) Run(ctx context.Context, src Source) error {
+	err := Each(ctx, src, r.Render)
+	r.Flush()
+	return err
+}
+
+// Render writes a single event. It returns the first write error encountered.
+func (r *Renderer) Render(event *components.SSEEventStream) error {
+	if event == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	r.inputs.Add(event)
+	parent := ParentToolCallID(event)
+	depth := r.depth(parent)
+
+	switch {
+	case event.SSEContentEvent != nil:
+		r.text(lineContent, parent, depth, event.SSEContentEvent.Data.Content, "")
+	case event.SSEThinkingEvent != nil:
+		r.thought(parent, depth, event.SSEThinkingEvent.Data.Content)
+	case event.SSEToolUseStartEvent != nil:
+		r.finishThinking(parent, depth)
+		r.depths[event.SSEToolUseStartEvent.Data.ID] = depth
+	case event.SSEToolExecutionStartEvent != nil:
+		data := event.SSEToolExecutionStartEvent.Data
+		r.depths[data.ToolCallID] = depth
+		r.status(data.ToolCallID, depth, nil, data.ToolName, data.Progress)
+	case event.SSEToolExecutionCompleteEvent != nil:
+		data := event.SSEToolExecutionCompleteEvent.Data
+		r.status(data.ToolCallID, depth, &data.Success, data.ToolName, data.Progress)
+		r.inputs.Forget(data.ToolCallID)
+	case event.SSEPermissionEvent != nil:
+		data := event.SSEPermissionEvent.Data
+		msg := fmt.Sprintf("%s %s wants to %s", r.style(ansiYellow, "?"), r.style(ansiBold, displayToolName(data.ToolName)), data.Description)
+		if data.Path != nil {
+			msg += " (" + *data.Path + ")"
+		}
+		r.line(depth, msg)
+	case event.SSENotificationEvent != nil:
+		data := event.SSENotificationEvent.Data
+		msg := fmt.Sprintf("%s %s: %s", r.style(ansiYellow, "!"), r.style(ansiBold, data.Title), data.Message)
+		if len(data.Choices) > 0 {
+			msg += " [" + strings.Join(data.Choices, " / ") + "]"
+		}
+		r.line(depth, msg)
+	case event.SSEErrorEvent != nil:
+		r.line(depth, r.style(ansiRed, "error: "+event.SSEErrorEvent.Data.Error))
+	case event.SSECompleteEvent != nil:
+		r.finishThinking(parent, depth)
+		if parent == nil {
+			r.endLine()
+		}
+	}
+
+	return r.err
+}
+
+// Flush terminates a partially written line.
+func (r *Renderer) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endLine()
+}
+
+func (r *Renderer) depth(parent *string) int {
+	if parent == nil {
+		return 0
+	}
+	return r.depths[*parent] + 1
+}
+
+// text streams a delta, starting a new indented line when the kind of output
+// or the agent producing it changes.
+func (r *Renderer) text(kind lineKind, parent *string, depth int, delta, color string) {
+	if delta == "" {
+		return
+	}
+	if kind == lineContent {
+		r.finishThinking(parent, depth)
+	}
+
+	prefix := strings.Repeat(r.indent, depth)
+	if r.kind != kind || r.lineDepth != depth || !sameString(r.lineParent, parent) {
+		r.endLine()
+		r.write(prefix)
+		r.kind, r.lineDepth, r.lineParent = kind, depth, parent
+	}
+
+	if prefix != "" {
+		delta = strings.ReplaceAll(delta, "\n", "\n"+prefix)
+	}
+	if color != "" && r.ansi {
+		delta = color + delta + ansiReset
+	}
+	r.write(delta)
+}
+
+func (r *Renderer) thought(parent *string, depth int, delta string) {
+	key := agentKey(parent)
+	if _, ok := r.thinking[key]; !ok {
+		r.thinking[key] = time.Now()
+		if r.reasoning == ReasoningCollapsed {
+			r.line(depth, r.style(ansiDim, "∴ Thinking…"))
+		}
+	}
+	if r.reasoning == ReasoningExpanded {
+		r.text(lineReasoning, parent, depth, delta, ansiDim)
+	}
+}
+
+func (r *Renderer) finishThinking(parent *string, depth int) {
+	key := agentKey(parent)
+	started, ok := r.thinking[key]
+	if !ok {
+		return
+	}
+	delete(r.thinking, key)
+	if r.reasoning == ReasoningHidden {
+		return
+	}
+	took := time.Since(started).Round(100 * time.Millisecond)
+	r.line(depth, r.style(ansiDim, fmt.Sprintf("∴ Thought for %s", took)))
+}
+
+// status writes the status line of a tool call, rewriting it in place when it
+// is still the last line and styling is enabled. success is nil while the tool
+// is running.
+func (r *Renderer) status(toolCallID string, depth int, success *bool, name components.ToolName, progress string) {
+	if success == nil && !r.ansi {
+		// Plain output cannot be rewritten, so only the outcome is written.
+		return
+	}
+
+	mark := r.style(ansiCyan, "●")
+	switch {
+	case success != nil && *success:
+		mark = r.style(ansiGreen, "✓")
+	case success != nil:
+		mark = r.style(ansiRed, "✗")
+	}
+
+	msg := mark + " " + r.style(ansiBold, displayToolName(name))
+	if in, ok := r.inputs.Input(toolCallID); ok {
+		if summary := summarizeArgs(in.Args); summary != "" {
+			msg += " " + summary
+		}
+	}
+	if progress != "" {
+		msg += r.style(ansiDim, " — "+progress)
+	}
+
+	if r.ansi && r.kind == lineStatus && r.statusCall == toolCallID {
+		r.write(ansiClearLine + strings.Repeat(r.indent, depth) + msg)
+		return
+	}
+	r.endLine()
+	r.write(strings.Repeat(r.indent, depth) + msg)
+	r.kind, r.lineDepth, r.lineParent = lineStatus, depth, nil
+	r.statusCall = toolCallID
+}
+
+// line writes a complete line of its own.
+func (r *Renderer) line(depth int, msg string) {
+	r.endLine()
+	r.write(strings.Repeat(r.indent, depth) + msg + "\n")
+}
+
+func (r *Renderer) endLine() {
+	if r.kind != lineNone {
+		r.write("\n")
+	}
+	r.kind, r.lineParent, r.statusCall = lineNone, nil, ""
+}
+
+func (r *Renderer) style(code, s string) string {
+	if !r.ansi {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (r *Renderer) write(s string) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = io.WriteString(r.w, s)
+}
+
+func agentKey(parent *string) string {
+	if parent == nil {
+		return ""
+	}
+	return *parent
+}
+
+// summaryKeys are the tool arguments that best describe a call, in order of
+// preference.
+var summaryKeys = []string{"command", "file_path", "path", "pattern", "url", "query", "description", "prompt"}
+
+const maxSummaryLen = 80
+
+func summarizeArgs(args map[string]any) string {
+	for _, key := range summaryKeys {
+		s, ok := args[key].(string)
+		if !ok || s == "" {
+			continue
+		}
+		s = strings.Join(strings.Fields(s), " ")
+		if r := []rune(s); len(r) > maxSummaryLen {
+			s = string(r[:maxSummaryLen-1]) + "…"
+		}
+		return s
+	}
+	return ""
+}
+
+func displayToolName(name components.ToolName) string {
+	if s := ToolName(name); s != "" {
+		return s
+	}
+	return "tool"
+}
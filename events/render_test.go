@@ -0,0 +1,48 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderer_Plain(t *testing.T) {
+	t.Parallel()
+	task := components.CreateToolNameCoreToolName(components.CoreToolNameTask)
+
+	feed := []components.SSEEventStream{
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "Let me "}}),
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "check."}}),
+		components.CreateSSEEventStreamToolUseStart(components.SSEToolUseStartEvent{Data: components.SSEToolUseStartEventData{ID: "t1", Name: bash()}}),
+		components.CreateSSEEventStreamToolUseParameterDelta(components.SSEToolUseParameterDeltaEvent{Data: components.SSEToolUseParameterDeltaEventData{ToolCallID: "t1", Input: `{"command":"ls -la"}`}}),
+		components.CreateSSEEventStreamToolExecutionStart(components.SSEToolExecutionStartEvent{Data: components.SSEToolExecutionStartEventData{ToolCallID: "t1", ToolName: bash(), Progress: "running"}}),
+		components.CreateSSEEventStreamToolExecutionComplete(components.SSEToolExecutionCompleteEvent{Data: components.SSEToolExecutionCompleteEventData{ToolCallID: "t1", ToolName: bash(), Progress: "done", Success: true}}),
+		components.CreateSSEEventStreamToolUseStart(components.SSEToolUseStartEvent{Data: components.SSEToolUseStartEventData{ID: "task1", Name: task}}),
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "sub\nagent", ParentToolCallID: ptr("task1")}}),
+		components.CreateSSEEventStreamPermission(components.SSEPermissionEvent{Data: components.SSEPermissionEventData{ID: "p1", ToolName: bash(), Description: "run rm", Path: ptr("/tmp"), ParentToolCallID: ptr("task1")}}),
+		components.CreateSSEEventStreamToolExecutionComplete(components.SSEToolExecutionCompleteEvent{Data: components.SSEToolExecutionCompleteEventData{ToolCallID: "task1", ToolName: task, Progress: "failed", Success: false}}),
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "Done."}}),
+		components.CreateSSEEventStreamComplete(components.SSECompleteEvent{Data: components.SSECompleteEventData{Done: true}}),
+	}
+
+	var out strings.Builder
+	r := NewRenderer(&out, WithReasoning(ReasoningHidden))
+	for i := range feed {
+		require.NoError(t, r.Render(&feed[i]))
+	}
+
+	want := strings.Join([]string{
+		"Let me check.",
+		"✓ Bash ls -la — done",
+		"  sub",
+		"  agent",
+		"  ? Bash wants to run rm (/tmp)",
+		"✗ Task — failed",
+		"Done.",
+		"",
+	}, "\n")
+	assert.Equal(t, want, out.String())
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/types/stream"
+)
+
+// Replay returns a session event stream that plays back a recording made with
+// EventStream.Record, decoded the same way as Streaming.StreamEvents decodes a
+// live stream. It is intended for golden-file tests of stream consumers.
+func Replay(ctx context.Context, recording io.Reader, opts ...stream.ReplayOption) *stream.EventStream[components.SSEEventStream] {
+	return stream.Replay(ctx, recording, func(se []byte) (components.SSEEventStream, error) {
+		var e components.SSEEventStream
+		if err := utils.UnmarshalJsonFromResponseBody(bytes.NewBuffer(se), &e, ""); err != nil {
+			return components.SSEEventStream{}, err
+		}
+		return e, nil
+	}, append([]stream.ReplayOption{stream.WithReplayStreamOptions(stream.WithDecoder(components.DecodeSSEEvent))}, opts...)...)
+}
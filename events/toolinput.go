@@ -0,0 +1,327 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// ToolInput is the best-effort view of a tool call's input parameters while
+// they are streaming.
+type ToolInput struct {
+	// Tool call identifier
+	ToolCallID string
+	// Tool name, once known from tool_use_start or the completed input
+	Name *components.ToolName
+	// Raw JSON received so far
+	Raw string
+	// Arguments parsed from Raw. While Complete is false, strings may be cut
+	// short and members that have not started streaming are absent. The map
+	// is shared between callers and must not be modified.
+	Args map[string]any
+	// Whether the full input has been received
+	Complete bool
+}
+
+// String returns a string argument by key.
+func (in ToolInput) String(key string) (string, bool) {
+	s, ok := in.Args[key].(string)
+	return s, ok
+}
+
+type toolInputState struct {
+	name     *components.ToolName
+	raw      strings.Builder
+	complete bool
+
+	args        argsParser
+	parsed      map[string]any
+	parsedBytes int
+}
+
+// ToolInputParser incrementally parses tool_use_parameter_delta input
+// fragments, keyed by tool call ID, so partially streamed arguments such as a
+// Bash command or a Write file path can be shown before the input completes.
+// Parsing happens lazily in Input, resumes where the previous call stopped,
+// and is cached until new fragments arrive. It is safe for concurrent use.
+type ToolInputParser struct {
+	mu     sync.Mutex
+	inputs map[string]*toolInputState
+}
+
+func NewToolInputParser() *ToolInputParser {
+	return &ToolInputParser{inputs: map[string]*toolInputState{}}
+}
+
+// Add records a tool_use_start, tool_use_parameter_delta or
+// tool_use_parameter_streaming_complete event. It returns the affected tool
+// call ID, or false for any other event.
+func (p *ToolInputParser) Add(event *components.SSEEventStream) (string, bool) {
+	if event == nil {
+		return "", false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case event.SSEToolUseStartEvent != nil:
+		data := event.SSEToolUseStartEvent.Data
+		name := data.Name
+		p.state(data.ID).name = &name
+		return data.ID, true
+	case event.SSEToolUseParameterDeltaEvent != nil:
+		data := event.SSEToolUseParameterDeltaEvent.Data
+		st := p.state(data.ToolCallID)
+		if !st.complete {
+			st.raw.WriteString(data.Input)
+		}
+		return data.ToolCallID, true
+	case event.SSEToolUseParameterStreamingCompleteEvent != nil:
+		data := event.SSEToolUseParameterStreamingCompleteEvent.Data
+		st := p.state(data.ID)
+		name := data.Name
+		st.name = &name
+		st.raw.Reset()
+		st.raw.WriteString(data.Input)
+		st.complete = true
+		st.args = argsParser{}
+		st.parsed = nil
+		st.parsedBytes = -1
+		return data.ID, true
+	}
+
+	return "", false
+}
+
+// Input returns the current view of a tool call's input.
+func (p *ToolInputParser) Input(toolCallID string) (ToolInput, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st, ok := p.inputs[toolCallID]
+	if !ok {
+		return ToolInput{}, false
+	}
+
+	raw := st.raw.String()
+	if st.parsedBytes != len(raw) {
+		if st.complete {
+			st.parsed = parseArgs(raw, true)
+		} else {
+			st.parsed = st.args.parse(raw)
+		}
+		st.parsedBytes = len(raw)
+	}
+
+	return ToolInput{
+		ToolCallID: toolCallID,
+		Name:       st.name,
+		Raw:        raw,
+		Args:       st.parsed,
+		Complete:   st.complete,
+	}, true
+}
+
+// Forget discards the state kept for a tool call.
+func (p *ToolInputParser) Forget(toolCallID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.inputs, toolCallID)
+}
+
+func (p *ToolInputParser) state(toolCallID string) *toolInputState {
+	st, ok := p.inputs[toolCallID]
+	if !ok {
+		st = &toolInputState{parsedBytes: -1}
+		p.inputs[toolCallID] = st
+	}
+	return st
+}
+
+func parseArgs(raw string, complete bool) map[string]any {
+	if complete {
+		var args map[string]any
+		if err := json.Unmarshal([]byte(raw), &args); err == nil {
+			return args
+		}
+	}
+
+	v, err := ParsePartialJSON(raw)
+	if err != nil {
+		return nil
+	}
+	args, _ := v.(map[string]any)
+	return args
+}
+
+// argsParser parses the input of a tool call, a JSON object, as it streams,
+// giving the same result as ParsePartialJSON on the input so far. Members are
+// parsed once they are complete and a string member is decoded as its bytes
+// arrive, so that streaming a large Write content costs time proportional to
+// its size. Other incomplete members are parsed again on each call.
+type argsParser struct {
+	// offset in the input of the next member or separator
+	pos     int
+	opened  bool
+	closed  bool
+	needSep bool
+	failed  bool
+	members map[string]any
+
+	// string member being decoded: offset of its opening quote, or zero,
+	// the offset decoded up to, and the value so far
+	strStart, strPos int
+	str              strings.Builder
+}
+
+// parse returns the arguments in raw, which extends the input of the previous
+// call. It returns nil for input that is not an object or is malformed.
+func (a *argsParser) parse(raw string) map[string]any {
+	if a.failed {
+		return nil
+	}
+
+	p := partialParser{s: raw, pos: a.pos}
+	var partialKey string
+	var partial any
+	hasPartial := false
+	for !a.closed {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+
+		switch {
+		case !a.opened:
+			if p.s[p.pos] != '{' {
+				return a.fail()
+			}
+			p.pos++
+			a.opened, a.pos = true, p.pos
+			a.members = map[string]any{}
+			continue
+		case a.needSep:
+			switch p.s[p.pos] {
+			case ',':
+				p.pos++
+				a.needSep, a.pos = false, p.pos
+			case '}':
+				p.pos++
+				a.closed, a.pos = true, p.pos
+			default:
+				return a.fail()
+			}
+			continue
+		case p.s[p.pos] == '}' && len(a.members) == 0:
+			p.pos++
+			a.closed, a.pos = true, p.pos
+			continue
+		case p.s[p.pos] != '"':
+			return a.fail()
+		}
+
+		key, complete := p.string()
+		if !complete {
+			break
+		}
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		if p.s[p.pos] != ':' {
+			return a.fail()
+		}
+		p.pos++
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+
+		var v any
+		if p.s[p.pos] == '"' {
+			v, p.pos, complete = a.string(raw, p.pos)
+			if !complete {
+				partialKey, partial, hasPartial = key, v, true
+				break
+			}
+		} else {
+			var ok bool
+			var err error
+			v, complete, ok, err = p.value()
+			if err != nil {
+				return a.fail()
+			}
+			if !complete {
+				partialKey, partial, hasPartial = key, v, ok
+				break
+			}
+		}
+		a.members[key] = v
+		a.needSep, a.pos = true, p.pos
+	}
+
+	if !a.opened {
+		return nil
+	}
+	if a.closed {
+		p.skipSpace()
+		if !p.eof() {
+			return a.fail()
+		}
+	}
+
+	args := make(map[string]any, len(a.members)+1)
+	for k, v := range a.members {
+		args[k] = v
+	}
+	if hasPartial {
+		args[partialKey] = partial
+	}
+	return args
+}
+
+func (a *argsParser) fail() map[string]any {
+	a.failed = true
+	a.members = nil
+	return nil
+}
+
+// string decodes the string starting with the quote at start, resuming where
+// the previous call for the same string stopped. It returns the value so far,
+// the offset after it and whether it is complete.
+func (a *argsParser) string(raw string, start int) (string, int, bool) {
+	if a.strStart != start {
+		a.strStart, a.strPos = start, start+1
+		a.str.Reset()
+	}
+
+	for i := a.strPos; i < len(raw); {
+		switch raw[i] {
+		case '"':
+			a.str.WriteString(raw[a.strPos:i])
+			v := a.str.String()
+			a.strStart = 0
+			a.str = strings.Builder{}
+			return v, i + 1, true
+		case '\\':
+			a.str.WriteString(raw[a.strPos:i])
+			r, n := unescape(raw[i:])
+			if n == 0 {
+				// Truncated escape: decode it once the rest arrives.
+				a.strPos = i
+				return a.str.String(), len(raw), false
+			}
+			a.str.WriteRune(r)
+			i += n
+			a.strPos = i
+		default:
+			i++
+		}
+	}
+	a.str.WriteString(raw[a.strPos:])
+	a.strPos = len(raw)
+	return a.str.String(), len(raw), false
+}
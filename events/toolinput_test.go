@@ -0,0 +1,75 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+func inputDelta(toolCallID, input string) *components.SSEEventStream {
+	event := components.CreateSSEEventStreamToolUseParameterDelta(components.SSEToolUseParameterDeltaEvent{
+		Data: components.SSEToolUseParameterDeltaEventData{ToolCallID: toolCallID, Input: input},
+	})
+	return &event
+}
+
+func TestToolInputParser_Incremental(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		`{"file_path": "/tmp/a.go", "content": "line\n\"q\" é 😀 end", "limit": 12.5, "force": true, "edits": [{"old": "x", "new": null}], "empty": {}}`,
+		` { } `,
+		`{"a": 1,}`,
+		`{"a" 1}`,
+		`[1, 2]`,
+		`{"a": 1} x`,
+	}
+	for _, input := range inputs {
+		p := NewToolInputParser()
+		for i := 0; i < len(input); i++ {
+			p.Add(inputDelta("t1", input[i:i+1]))
+			got, ok := p.Input("t1")
+			require.True(t, ok)
+
+			want, err := ParsePartialJSON(input[:i+1])
+			wantArgs, _ := want.(map[string]any)
+			if err != nil {
+				wantArgs = nil
+			}
+			require.Equal(t, wantArgs, got.Args, "after %q", input[:i+1])
+		}
+	}
+}
+
+func TestToolInputParser_Complete(t *testing.T) {
+	t.Parallel()
+	p := NewToolInputParser()
+	p.Add(inputDelta("t1", `{"command": "ls`))
+	in, _ := p.Input("t1")
+	assert.Equal(t, map[string]any{"command": "ls"}, in.Args)
+
+	complete := components.CreateSSEEventStreamToolUseParameterStreamingComplete(components.SSEToolUseParameterStreamingCompleteEvent{
+		Data: components.SSEToolUseParameterStreamingCompleteEventData{ID: "t1", Name: components.CreateToolNameStr("Bash"), Input: `{"command": "ls -l"}`},
+	})
+	p.Add(&complete)
+	in, _ = p.Input("t1")
+	assert.True(t, in.Complete)
+	assert.Equal(t, map[string]any{"command": "ls -l"}, in.Args)
+	s, _ := in.String("command")
+	assert.Equal(t, "ls -l", s)
+}
+
+func BenchmarkToolInputParser(b *testing.B) {
+	content := strings.Repeat(`some file content with \"escapes\"\n`, 1<<15)
+	for i := 0; i < b.N; i++ {
+		p := NewToolInputParser()
+		p.Add(inputDelta("t1", `{"file_path": "/tmp/a.go", "content": "`))
+		for j := 0; j < len(content); j += 64 {
+			p.Add(inputDelta("t1", content[j:min(j+64, len(content))]))
+			p.Input("t1")
+		}
+	}
+}
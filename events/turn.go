@@ -0,0 +1,361 @@
+package events
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// ToolCall is the assembled state of a single tool call within a turn.
+type ToolCall struct {
+	// Unique tool call identifier
+	ID string
+	// Tool name - either a core tool or MCP tool following {serverName}_{toolName} pattern
+	Name components.ToolName
+	// JSON-encoded tool input. While InputComplete is false this holds the
+	// concatenated parameter deltas received so far and may not be parseable.
+	Input string
+	// Whether the full tool input has been received
+	InputComplete bool
+	// Latest execution progress description
+	Progress string
+	// Whether tool execution has started
+	Running bool
+	// Whether tool execution has finished
+	Finished bool
+	// Whether tool execution succeeded, set once Finished
+	Success *bool
+	// ID of the assistant message this tool call belongs to
+	AssistantMessageID *string
+	// ID of the parent tool call for subagent tool calls
+	ParentToolCallID *string
+	StartedAt        time.Time
+	FinishedAt       time.Time
+}
+
+// Args returns the best-effort parsed tool input, see ParsePartialJSON.
+func (c ToolCall) Args() map[string]any {
+	return parseArgs(c.Input, c.InputComplete)
+}
+
+// ToolCallData converts the tool call into the shape used by message history.
+// The stream does not carry a tool type, so Type is left empty.
+func (c ToolCall) ToolCallData() components.ToolCallData {
+	data := components.ToolCallData{
+		Finished: c.Finished,
+		ID:       c.ID,
+		Input:    c.Input,
+		Name:     c.Name,
+	}
+	if c.Success != nil {
+		isError := !*c.Success
+		data.IsError = &isError
+		progress := c.Progress
+		data.Result = &progress
+	}
+	return data
+}
+
+// Turn is a snapshot of an assistant turn rebuilt from stream deltas.
+type Turn struct {
+	// ID of the user message that started the turn, if it was observed
+	UserMessageID *string
+	// Content of the user message that started the turn, if it was observed
+	UserInput string
+	// Completed assistant message identifier, set once Done
+	MessageID *string
+	// Assistant response text received so far
+	Content string
+	// Reasoning text received so far
+	Reasoning string
+	// Duration of reasoning process in milliseconds, set once Done
+	ReasoningDuration *int64
+	// Tool calls in the order they were first seen
+	ToolCalls []ToolCall
+	// Error events received during the turn
+	Errors []components.SSEErrorEventData
+	// Whether the complete event for the turn has been received
+	Done       bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// ToolCall returns the tool call with the given ID, if present.
+func (t *Turn) ToolCall(id string) (ToolCall, bool) {
+	for _, c := range t.ToolCalls {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return ToolCall{}, false
+}
+
+// BackendMessage converts the turn into the shape used by message history.
+// Usage and cost are not part of the stream and are left unset.
+func (t *Turn) BackendMessage(sessionID string) components.BackendMessage {
+	msg := components.BackendMessage{
+		Role:      "assistant",
+		SessionID: sessionID,
+		UserInput: t.UserInput,
+	}
+	if t.MessageID != nil {
+		msg.ID = *t.MessageID
+	}
+	if t.Content != "" {
+		content := t.Content
+		msg.AssistantResponse = &content
+	}
+	if t.Reasoning != "" {
+		reasoning := t.Reasoning
+		msg.Reasoning = &reasoning
+	}
+	msg.ReasoningDuration = t.ReasoningDuration
+	for _, c := range t.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, c.ToolCallData())
+	}
+	return msg
+}
+
+func (t *Turn) clone() Turn {
+	out := *t
+	out.ToolCalls = append([]ToolCall(nil), t.ToolCalls...)
+	out.Errors = append([]components.SSEErrorEventData(nil), t.Errors...)
+	return out
+}
+
+// Assembler rebuilds assistant turns from the fragmented events of a session
+// stream. An assembler follows a single agent: the root agent by default, or
+// one subagent when created with NewSubagentAssembler. Events from other
+// agents are ignored. It is safe for concurrent use, so one goroutine can feed
+// events while others take snapshots.
+type Assembler struct {
+	parentToolCallID *string
+
+	mu      sync.Mutex
+	turn    Turn
+	content strings.Builder
+	reason  strings.Builder
+	inputs  map[string]*strings.Builder
+	active  bool
+}
+
+// NewAssembler creates an assembler for the root agent's turns.
+func NewAssembler() *Assembler {
+	return &Assembler{}
+}
+
+// NewSubagentAssembler creates an assembler for the subagent spawned by the
+// given Task tool call.
+func NewSubagentAssembler(parentToolCallID string) *Assembler {
+	return &Assembler{parentToolCallID: &parentToolCallID}
+}
+
+// Add applies an event to the current turn. It reports whether the event
+// belonged to the assembler's agent and changed its state.
+func (a *Assembler) Add(event *components.SSEEventStream) bool {
+	if event == nil || !a.inScope(ParentToolCallID(event)) {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+
+	switch {
+	case event.SSEUserMessageCreatedEvent != nil:
+		data := event.SSEUserMessageCreatedEvent.Data
+		a.reset(now)
+		a.turn.UserMessageID = &data.MessageID
+		a.turn.UserInput = data.Content
+	case event.SSEThinkingEvent != nil:
+		a.begin(now)
+		a.reason.WriteString(event.SSEThinkingEvent.Data.Content)
+		a.turn.Reasoning = a.reason.String()
+	case event.SSEContentEvent != nil:
+		a.begin(now)
+		a.content.WriteString(event.SSEContentEvent.Data.Content)
+		a.turn.Content = a.content.String()
+	case event.SSEToolUseStartEvent != nil:
+		data := event.SSEToolUseStartEvent.Data
+		a.begin(now)
+		c := a.toolCall(data.ID, now)
+		c.Name = data.Name
+		c.AssistantMessageID = data.AssistantMessageID
+		c.ParentToolCallID = data.ParentToolCallID
+	case event.SSEToolUseParameterDeltaEvent != nil:
+		data := event.SSEToolUseParameterDeltaEvent.Data
+		a.begin(now)
+		c := a.toolCall(data.ToolCallID, now)
+		if c.InputComplete {
+			break
+		}
+		b := a.inputs[data.ToolCallID]
+		if b == nil {
+			b = &strings.Builder{}
+			a.inputs[data.ToolCallID] = b
+		}
+		b.WriteString(data.Input)
+		c.Input = b.String()
+	case event.SSEToolUseParameterStreamingCompleteEvent != nil:
+		data := event.SSEToolUseParameterStreamingCompleteEvent.Data
+		a.begin(now)
+		c := a.toolCall(data.ID, now)
+		c.Name = data.Name
+		c.Input = data.Input
+		c.InputComplete = true
+		delete(a.inputs, data.ID)
+	case event.SSEToolExecutionStartEvent != nil:
+		data := event.SSEToolExecutionStartEvent.Data
+		a.begin(now)
+		c := a.toolCall(data.ToolCallID, now)
+		c.Name = data.ToolName
+		c.Progress = data.Progress
+		c.Running = true
+	case event.SSEToolExecutionCompleteEvent != nil:
+		data := event.SSEToolExecutionCompleteEvent.Data
+		a.begin(now)
+		c := a.toolCall(data.ToolCallID, now)
+		c.Name = data.ToolName
+		c.Progress = data.Progress
+		c.Running = false
+		c.Finished = true
+		success := data.Success
+		c.Success = &success
+		c.FinishedAt = now
+	case event.SSEErrorEvent != nil:
+		a.begin(now)
+		a.turn.Errors = append(a.turn.Errors, event.SSEErrorEvent.Data)
+	case event.SSECompleteEvent != nil:
+		data := event.SSECompleteEvent.Data
+		a.begin(now)
+		if data.Content != nil {
+			a.turn.Content = *data.Content
+		}
+		if data.Reasoning != nil {
+			a.turn.Reasoning = *data.Reasoning
+		}
+		a.turn.MessageID = data.MessageID
+		a.turn.ReasoningDuration = data.ReasoningDuration
+		a.turn.Done = true
+		a.turn.FinishedAt = now
+		a.active = false
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Collect feeds events from src into the assembler until the current turn
+// completes, ctx is done, or the stream ends, and returns the final snapshot.
+func (a *Assembler) Collect(ctx context.Context, src Source) (Turn, error) {
+	complete := false
+	err := Each(ctx, src, func(event *components.SSEEventStream) error {
+		if a.Add(event) && event.SSECompleteEvent != nil {
+			complete = true
+			return ErrStop
+		}
+		return nil
+	})
+	if err == nil && !complete {
+		err = io.ErrUnexpectedEOF
+	}
+	return a.Snapshot(), err
+}
+
+// Snapshot returns a copy of the current turn.
+func (a *Assembler) Snapshot() Turn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.turn.clone()
+}
+
+// Done reports whether the current turn has completed.
+func (a *Assembler) Done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.turn.Done
+}
+
+// Reset discards the current turn.
+func (a *Assembler) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reset(time.Time{})
+}
+
+func (a *Assembler) inScope(parent *string) bool {
+	if a.parentToolCallID == nil || parent == nil {
+		return a.parentToolCallID == nil && parent == nil
+	}
+	return *a.parentToolCallID == *parent
+}
+
+// begin starts a new turn if the previous one already completed, so that an
+// assembler can follow a session across several turns.
+func (a *Assembler) begin(now time.Time) {
+	if a.active {
+		return
+	}
+	if a.turn.Done || a.turn.StartedAt.IsZero() {
+		a.reset(now)
+	}
+	a.active = true
+}
+
+func (a *Assembler) reset(now time.Time) {
+	a.turn = Turn{StartedAt: now}
+	a.content.Reset()
+	a.reason.Reset()
+	a.inputs = map[string]*strings.Builder{}
+	a.active = !now.IsZero()
+}
+
+func (a *Assembler) toolCall(id string, now time.Time) *ToolCall {
+	for i := range a.turn.ToolCalls {
+		if a.turn.ToolCalls[i].ID == id {
+			return &a.turn.ToolCalls[i]
+		}
+	}
+	a.turn.ToolCalls = append(a.turn.ToolCalls, ToolCall{ID: id, StartedAt: now})
+	return &a.turn.ToolCalls[len(a.turn.ToolCalls)-1]
+}
+
+// ParentToolCallID returns the parent tool call ID carried by an event, or nil
+// for events from the root agent and events that carry none.
+func ParentToolCallID(event *components.SSEEventStream) *string {
+	switch {
+	case event == nil:
+		return nil
+	case event.SSEErrorEvent != nil:
+		return event.SSEErrorEvent.Data.ParentToolCallID
+	case event.SSECompleteEvent != nil:
+		return event.SSECompleteEvent.Data.ParentToolCallID
+	case event.SSEThinkingEvent != nil:
+		return event.SSEThinkingEvent.Data.ParentToolCallID
+	case event.SSEContentEvent != nil:
+		return event.SSEContentEvent.Data.ParentToolCallID
+	case event.SSEToolUseStartEvent != nil:
+		return event.SSEToolUseStartEvent.Data.ParentToolCallID
+	case event.SSEToolUseParameterStreamingCompleteEvent != nil:
+		return event.SSEToolUseParameterStreamingCompleteEvent.Data.ParentToolCallID
+	case event.SSEToolUseParameterDeltaEvent != nil:
+		return event.SSEToolUseParameterDeltaEvent.Data.ParentToolCallID
+	case event.SSEToolExecutionStartEvent != nil:
+		return event.SSEToolExecutionStartEvent.Data.ParentToolCallID
+	case event.SSEToolExecutionCompleteEvent != nil:
+		return event.SSEToolExecutionCompleteEvent.Data.ParentToolCallID
+	case event.SSEPermissionEvent != nil:
+		return event.SSEPermissionEvent.Data.ParentToolCallID
+	case event.SSENotificationEvent != nil:
+		return event.SSENotificationEvent.Data.ParentToolCallID
+	case event.SSEUserMessageCreatedEvent != nil:
+		return event.SSEUserMessageCreatedEvent.Data.ParentToolCallID
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func bash() components.ToolName {
+	return components.CreateToolNameCoreToolName(components.CoreToolNameBash)
+}
+
+func TestAssembler_RebuildsTurn(t *testing.T) {
+	t.Parallel()
+	a := NewAssembler()
+
+	feed := []components.SSEEventStream{
+		components.CreateSSEEventStreamUserMessageCreated(components.SSEUserMessageCreatedEvent{Data: components.SSEUserMessageCreatedEventData{MessageID: "u1", Content: "list files"}}),
+		components.CreateSSEEventStreamThinking(components.SSEThinkingEvent{Data: components.SSEThinkingEventData{Content: "need "}}),
+		components.CreateSSEEventStreamThinking(components.SSEThinkingEvent{Data: components.SSEThinkingEventData{Content: "ls"}}),
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "Running "}}),
+		components.CreateSSEEventStreamToolUseStart(components.SSEToolUseStartEvent{Data: components.SSEToolUseStartEventData{ID: "t1", Name: bash()}}),
+		components.CreateSSEEventStreamToolUseParameterDelta(components.SSEToolUseParameterDeltaEvent{Data: components.SSEToolUseParameterDeltaEventData{ToolCallID: "t1", Input: `{"command":`}}),
+		components.CreateSSEEventStreamToolUseParameterDelta(components.SSEToolUseParameterDeltaEvent{Data: components.SSEToolUseParameterDeltaEventData{ToolCallID: "t1", Input: `"ls"}`}}),
+		// Subagent output must not leak into the root turn.
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "nested", ParentToolCallID: ptr("t0")}}),
+		components.CreateSSEEventStreamToolExecutionStart(components.SSEToolExecutionStartEvent{Data: components.SSEToolExecutionStartEventData{ToolCallID: "t1", ToolName: bash(), Progress: "running"}}),
+		components.CreateSSEEventStreamToolExecutionComplete(components.SSEToolExecutionCompleteEvent{Data: components.SSEToolExecutionCompleteEventData{ToolCallID: "t1", ToolName: bash(), Progress: "done", Success: true}}),
+		components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "ls."}}),
+	}
+	for i := range feed {
+		a.Add(&feed[i])
+	}
+
+	turn := a.Snapshot()
+	assert.False(t, turn.Done)
+	assert.Equal(t, "list files", turn.UserInput)
+	assert.Equal(t, "need ls", turn.Reasoning)
+	assert.Equal(t, "Running ls.", turn.Content)
+	require.Len(t, turn.ToolCalls, 1)
+	call := turn.ToolCalls[0]
+	assert.Equal(t, `{"command":"ls"}`, call.Input)
+	assert.True(t, call.Finished)
+	assert.Equal(t, "done", call.Progress)
+	require.NotNil(t, call.Success)
+	assert.True(t, *call.Success)
+
+	complete := components.CreateSSEEventStreamComplete(components.SSECompleteEvent{Data: components.SSECompleteEventData{
+		Done:              true,
+		MessageID:         ptr("m1"),
+		Content:           ptr("Running ls. Done."),
+		ReasoningDuration: ptr(int64(120)),
+	}})
+	require.True(t, a.Add(&complete))
+
+	turn = a.Snapshot()
+	assert.True(t, turn.Done)
+	assert.Equal(t, "Running ls. Done.", turn.Content)
+	assert.Equal(t, "need ls", turn.Reasoning)
+	assert.Equal(t, int64(120), *turn.ReasoningDuration)
+
+	msg := turn.BackendMessage("s1")
+	assert.Equal(t, "m1", msg.ID)
+	require.Len(t, msg.ToolCalls, 1)
+	assert.False(t, *msg.ToolCalls[0].IsError)
+
+	// The next turn starts fresh.
+	next := components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "again"}})
+	a.Add(&next)
+	turn = a.Snapshot()
+	assert.False(t, turn.Done)
+	assert.Equal(t, "again", turn.Content)
+	assert.Empty(t, turn.ToolCalls)
+}
+
+func TestAssembler_Subagent(t *testing.T) {
+	t.Parallel()
+	a := NewSubagentAssembler("task1")
+
+	root := components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "root"}})
+	child := components.CreateSSEEventStreamContent(components.SSEContentEvent{Data: components.SSEContentEventData{Content: "child", ParentToolCallID: ptr("task1")}})
+
+	assert.False(t, a.Add(&root))
+	assert.True(t, a.Add(&child))
+	assert.Equal(t, "child", a.Snapshot().Content)
+}
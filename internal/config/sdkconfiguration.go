@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package config
 
 import (
@@ -19,6 +17,15 @@ type SDKConfiguration struct {
 	UserAgent   string
 	RetryConfig *retry.Config
 	Timeout     *time.Duration
+	// MaxEventSize overrides the largest server-sent event accepted by event
+	// streams. Nil keeps the stream package default; zero or less is unbounded.
+	MaxEventSize *int
+	// StreamIdleTimeout enables the event stream liveness watchdog. Nil or
+	// zero disables it.
+	StreamIdleTimeout *time.Duration
+	// CancelOnContextDone makes SDK helpers that wait on an agent turn cancel
+	// processing on the server when their context is done.
+	CancelOnContextDone bool
 }
 
 func (c *SDKConfiguration) GetServerDetails() (string, map[string]string) {
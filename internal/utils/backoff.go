@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/retry"
+)
+
+// NextInterval returns the jittered wait before the given zero-based retry
+// attempt under the backoff strategy s.
+func NextInterval(s *retry.BackoffStrategy, attempt int) time.Duration {
+	return nextInterval(s, attempt)
+}
@@ -0,0 +1,16 @@
+package utils
+
+import "sync/atomic"
+
+var lenientEnums atomic.Bool
+
+// SetLenientEnums controls whether generated enum types accept values outside
+// their known set instead of failing to unmarshal.
+func SetLenientEnums(enabled bool) {
+	lenientEnums.Store(enabled)
+}
+
+// LenientEnums reports whether lenient enum decoding is enabled.
+func LenientEnums() bool {
+	return lenientEnums.Load()
+}
@@ -0,0 +1,34 @@
+package apierrors
+
+import (
+	"fmt"
+)
+
+// CancelError is returned when a caller stopped waiting on a session and the
+// SDK asked the server to cancel processing on its behalf. It unwraps to the
+// cause, typically context.Canceled or context.DeadlineExceeded.
+type CancelError struct {
+	SessionID string
+	// Why the caller stopped waiting
+	Cause error
+	// Whether the server confirmed that processing was cancelled
+	Acknowledged bool
+	// Failure of the cancel request itself, if any
+	Err error
+}
+
+var _ error = &CancelError{}
+
+func (e *CancelError) Error() string {
+	switch {
+	case e.Acknowledged:
+		return fmt.Sprintf("session %s: %v: processing cancelled", e.SessionID, e.Cause)
+	case e.Err != nil:
+		return fmt.Sprintf("session %s: %v: cancelling processing failed: %v", e.SessionID, e.Cause, e.Err)
+	}
+	return fmt.Sprintf("session %s: %v: cancel not acknowledged", e.SessionID, e.Cause)
+}
+
+func (e *CancelError) Unwrap() error {
+	return e.Cause
+}
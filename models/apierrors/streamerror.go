@@ -0,0 +1,61 @@
+package apierrors
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// StreamError is an error event received on a session event stream.
+type StreamError struct {
+	Message string
+	// Error type classification
+	Type *string
+	// Current retry attempt number
+	Attempt *int64
+	// Maximum number of retry attempts
+	MaxAttempts *int64
+	// How long the server asks clients to wait before retrying
+	RetryAfter *time.Duration
+	// ID of the parent tool call for errors raised by a subagent
+	ParentToolCallID *string
+}
+
+var _ error = &StreamError{}
+
+// NewStreamError converts the payload of an error event into a StreamError.
+func NewStreamError(data components.SSEErrorEventData) *StreamError {
+	e := &StreamError{
+		Message:          data.Error,
+		Type:             data.Type,
+		Attempt:          data.Attempt,
+		MaxAttempts:      data.MaxAttempts,
+		ParentToolCallID: data.ParentToolCallID,
+	}
+	if data.RetryAfter != nil {
+		d := time.Duration(*data.RetryAfter) * time.Millisecond
+		e.RetryAfter = &d
+	}
+	return e
+}
+
+func (e *StreamError) Error() string {
+	msg := e.Message
+	if e.Type != nil {
+		msg = fmt.Sprintf("%s: %s", *e.Type, msg)
+	}
+	if e.Attempt != nil && e.MaxAttempts != nil {
+		msg = fmt.Sprintf("%s (attempt %d of %d)", msg, *e.Attempt, *e.MaxAttempts)
+	}
+	return "stream error: " + msg
+}
+
+// Temporary reports whether the server indicated that it will retry, either
+// by advertising a retry delay or an attempt count below the maximum.
+func (e *StreamError) Temporary() bool {
+	if e.RetryAfter != nil {
+		return true
+	}
+	return e.Attempt != nil && e.MaxAttempts != nil && *e.Attempt < *e.MaxAttempts
+}
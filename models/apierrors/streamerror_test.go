@@ -0,0 +1,47 @@
+package apierrors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestNewStreamError(t *testing.T) {
+	t.Parallel()
+	err := NewStreamError(components.SSEErrorEventData{
+		Error:       "rate limited",
+		Type:        ptr("llm"),
+		Attempt:     ptr(int64(1)),
+		MaxAttempts: ptr(int64(3)),
+		RetryAfter:  ptr(int64(1500)),
+	})
+	assert.Equal(t, "stream error: llm: rate limited (attempt 1 of 3)", err.Error())
+	assert.Equal(t, ptr(1500*time.Millisecond), err.RetryAfter)
+}
+
+func TestStreamError_Temporary(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		data components.SSEErrorEventData
+		want bool
+	}{
+		{"no hint", components.SSEErrorEventData{Error: "boom"}, false},
+		{"retry after", components.SSEErrorEventData{Error: "boom", RetryAfter: ptr(int64(100))}, true},
+		{"attempts left", components.SSEErrorEventData{Error: "boom", Attempt: ptr(int64(1)), MaxAttempts: ptr(int64(3))}, true},
+		{"last attempt", components.SSEErrorEventData{Error: "boom", Attempt: ptr(int64(3)), MaxAttempts: ptr(int64(3))}, false},
+		{"attempt only", components.SSEErrorEventData{Error: "boom", Attempt: ptr(int64(1))}, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, NewStreamError(tt.data).Temporary())
+		})
+	}
+}
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
@@ -34,6 +32,10 @@ func (e *CallbackType) UnmarshalJSON(data []byte) error {
 		*e = CallbackType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = CallbackType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for CallbackType: %v", v)
 	}
 }
@@ -1,10 +1,9 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
 )
 
 // CallbackResultDataCallbackType - Type of callback executed
@@ -33,6 +32,10 @@ func (e *CallbackResultDataCallbackType) UnmarshalJSON(data []byte) error {
 		*e = CallbackResultDataCallbackType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = CallbackResultDataCallbackType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for CallbackResultDataCallbackType: %v", v)
 	}
 }
@@ -1,10 +1,9 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
 )
 
 // CoreToolName - Core built-in tool names
@@ -60,6 +59,10 @@ func (e *CoreToolName) UnmarshalJSON(data []byte) error {
 		*e = CoreToolName(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = CoreToolName(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for CoreToolName: %v", v)
 	}
 }
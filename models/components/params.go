@@ -0,0 +1,328 @@
+package components
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNoParams is returned when decoding a permission request that carries no
+// parameters.
+var ErrNoParams = errors.New("permission request has no params")
+
+// NewParams returns Params holding the JSON encoding of v, for example a
+// BashParams.
+func NewParams(v any) (*Params, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Params{raw: raw}, nil
+}
+
+// Raw returns the parameters as sent by the server, or nil if there are none.
+// The returned slice must not be modified.
+func (p *Params) Raw() json.RawMessage {
+	if p == nil {
+		return nil
+	}
+	return p.raw
+}
+
+// Decode decodes the parameters into v, which is useful for MCP tools whose
+// parameters are known to the caller. Unknown fields are ignored.
+func (p *Params) Decode(v any) error {
+	if p == nil || len(p.raw) == 0 {
+		return ErrNoParams
+	}
+	return json.Unmarshal(p.raw, v)
+}
+
+// Map decodes the parameters into a generic map.
+func (p *Params) Map() (map[string]any, error) {
+	var m map[string]any
+	if err := p.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BashParams are the parameters of the Bash tool.
+type BashParams struct {
+	// The command to execute
+	Command string `json:"command"`
+	// Timeout in milliseconds
+	Timeout *int64 `json:"timeout,omitempty"`
+}
+
+// EditParams are the parameters of the Edit tool.
+type EditParams struct {
+	// Absolute path to the file
+	FilePath string `json:"file_path"`
+	// Text to replace, empty to create the file
+	OldString string `json:"old_string"`
+	// Replacement text, empty to delete OldString
+	NewString string `json:"new_string"`
+	// Whether to replace all occurrences
+	ReplaceAll *bool `json:"replace_all,omitempty"`
+}
+
+// WriteParams are the parameters of the Write tool.
+type WriteParams struct {
+	// Path to the file
+	FilePath string `json:"file_path"`
+	// Content to write
+	Content string `json:"content"`
+}
+
+// ReadTextParams are the parameters of the ReadText tool.
+type ReadTextParams struct {
+	// Absolute path or HTTP/HTTPS URL
+	FilePath string `json:"file_path"`
+	// Line number to start from, 0-based
+	Offset *int64 `json:"offset,omitempty"`
+	// Number of lines to read
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// GrepParams are the parameters of the Grep tool.
+type GrepParams struct {
+	// Regex pattern to search for
+	Pattern string `json:"pattern"`
+	// File or directory to search
+	Path *string `json:"path,omitempty"`
+	// Glob pattern to filter files
+	Glob *string `json:"glob,omitempty"`
+	// File type filter
+	Type *string `json:"type,omitempty"`
+	// content, files_with_matches or count
+	OutputMode *string `json:"output_mode,omitempty"`
+	// Case insensitive search
+	CaseInsensitive *bool `json:"-i,omitempty"`
+	// Show line numbers
+	LineNumbers *bool `json:"-n,omitempty"`
+	// Lines after each match
+	After *int64 `json:"-A,omitempty"`
+	// Lines before each match
+	Before *int64 `json:"-B,omitempty"`
+	// Lines around each match
+	Context *int64 `json:"-C,omitempty"`
+	// Enable multiline mode
+	Multiline *bool `json:"multiline,omitempty"`
+	// Limit on output lines or entries
+	HeadLimit *int64 `json:"head_limit,omitempty"`
+}
+
+// GlobParams are the parameters of the Glob tool.
+type GlobParams struct {
+	// Glob pattern
+	Pattern string `json:"pattern"`
+	// Directory to search
+	Path *string `json:"path,omitempty"`
+}
+
+// ReadMediaParams are the parameters of the ReadMedia tool.
+type ReadMediaParams struct {
+	// Absolute path or URL
+	FilePath string `json:"file_path"`
+	// image, audio, video or pdf
+	MediaType string `json:"media_type"`
+	// Analysis prompt
+	Prompt string `json:"prompt"`
+	// PDF page selection, such as 1-3,7
+	PDFPages *string `json:"pdf_pages,omitempty"`
+	// Video time interval, such as 00:20:50-00:26:10
+	VideoInterval *string `json:"video_interval,omitempty"`
+}
+
+// SearchParams are the parameters of the Search tool.
+type SearchParams struct {
+	// Search query
+	Query string `json:"query"`
+	// web, images or videos
+	SearchType *string `json:"search_type,omitempty"`
+	// Only include results from these domains
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	// Exclude results from these domains
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+	// strict, moderate or off
+	SafeSearch *string `json:"safesearch,omitempty"`
+	// Enable spell correction
+	SpellCheck *bool `json:"spellcheck,omitempty"`
+}
+
+// TodoItem is an entry of TodoWriteParams.
+type TodoItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	// pending, in_progress or completed
+	Status string `json:"status"`
+	// high, medium or low
+	Priority string `json:"priority"`
+}
+
+// TodoWriteParams are the parameters of the TodoWrite tool.
+type TodoWriteParams struct {
+	Todos []TodoItem `json:"todos"`
+}
+
+// ExitPlanModeParams are the parameters of the ExitPlanMode tool.
+type ExitPlanModeParams struct {
+	// The plan, in markdown
+	Plan string `json:"plan"`
+}
+
+// ShowOutput is an entry of ShowParams.
+type ShowOutput struct {
+	// image, video, audio, pdf, csv, markdown, json or status
+	Type string `json:"type"`
+	// HTTP/HTTPS URL for file types
+	Path *string `json:"path,omitempty"`
+	// Inline content for markdown, json and status
+	Data *string `json:"data,omitempty"`
+	// Display title
+	Title string `json:"title"`
+	// Start time in seconds for video and audio segments
+	StartTime *int64 `json:"startTime,omitempty"`
+	// Duration in seconds for video and audio segments
+	Duration *int64 `json:"duration,omitempty"`
+}
+
+// ShowParams are the parameters of the Show tool.
+type ShowParams struct {
+	Outputs []ShowOutput `json:"outputs"`
+}
+
+// TaskParams are the parameters of the Task tool.
+type TaskParams struct {
+	// Short task description
+	Description string `json:"description"`
+	// Detailed task instructions
+	Prompt string `json:"prompt"`
+	// Agent type, such as general-purpose
+	SubagentType string `json:"subagent_type"`
+	// Model override
+	Model *string `json:"model,omitempty"`
+	// Maximum agentic turns before stopping
+	MaxTurns *int64 `json:"max_turns,omitempty"`
+	// Whether to run the task in the background
+	RunInBackground *bool `json:"run_in_background,omitempty"`
+}
+
+func decodeParams[T any](p *Params, tool CoreToolName) (*T, error) {
+	var v T
+	if err := p.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding %s params: %w", tool, err)
+	}
+	return &v, nil
+}
+
+// Bash decodes the parameters of a Bash request.
+func (p *Params) Bash() (*BashParams, error) {
+	return decodeParams[BashParams](p, CoreToolNameBash)
+}
+
+// Edit decodes the parameters of an Edit request.
+func (p *Params) Edit() (*EditParams, error) {
+	return decodeParams[EditParams](p, CoreToolNameEdit)
+}
+
+// Write decodes the parameters of a Write request.
+func (p *Params) Write() (*WriteParams, error) {
+	return decodeParams[WriteParams](p, CoreToolNameWrite)
+}
+
+// ReadText decodes the parameters of a ReadText request.
+func (p *Params) ReadText() (*ReadTextParams, error) {
+	return decodeParams[ReadTextParams](p, CoreToolNameReadText)
+}
+
+// Grep decodes the parameters of a Grep request.
+func (p *Params) Grep() (*GrepParams, error) {
+	return decodeParams[GrepParams](p, CoreToolNameGrep)
+}
+
+// Glob decodes the parameters of a Glob request.
+func (p *Params) Glob() (*GlobParams, error) {
+	return decodeParams[GlobParams](p, CoreToolNameGlob)
+}
+
+// ReadMedia decodes the parameters of a ReadMedia request.
+func (p *Params) ReadMedia() (*ReadMediaParams, error) {
+	return decodeParams[ReadMediaParams](p, CoreToolNameReadMedia)
+}
+
+// Search decodes the parameters of a Search request.
+func (p *Params) Search() (*SearchParams, error) {
+	return decodeParams[SearchParams](p, CoreToolNameSearch)
+}
+
+// TodoWrite decodes the parameters of a TodoWrite request.
+func (p *Params) TodoWrite() (*TodoWriteParams, error) {
+	return decodeParams[TodoWriteParams](p, CoreToolNameTodoWrite)
+}
+
+// ExitPlanMode decodes the parameters of an ExitPlanMode request.
+func (p *Params) ExitPlanMode() (*ExitPlanModeParams, error) {
+	return decodeParams[ExitPlanModeParams](p, CoreToolNameExitPlanMode)
+}
+
+// Show decodes the parameters of a Show request.
+func (p *Params) Show() (*ShowParams, error) {
+	return decodeParams[ShowParams](p, CoreToolNameShow)
+}
+
+// Task decodes the parameters of a Task request.
+func (p *Params) Task() (*TaskParams, error) {
+	return decodeParams[TaskParams](p, CoreToolNameTask)
+}
+
+// ToolParams decodes Params according to ToolName: a *BashParams for Bash, a
+// *WriteParams for Write, and so on for every core tool. For MCP tools and
+// core tools this version of the SDK does not know, it returns the raw JSON
+// as a json.RawMessage. It returns ErrNoParams when the request has none.
+func (s *SSEPermissionEventData) ToolParams() (any, error) {
+	params := s.GetParams()
+	if len(params.Raw()) == 0 {
+		return nil, ErrNoParams
+	}
+
+	name := s.GetToolName()
+	tool := name.CoreToolName
+	if tool == nil && name.Str != nil {
+		// A plain string may still name a core tool.
+		t := CoreToolName(*name.Str)
+		tool = &t
+	}
+	if tool == nil {
+		return params.Raw(), nil
+	}
+
+	switch *tool {
+	case CoreToolNameBash:
+		return params.Bash()
+	case CoreToolNameEdit:
+		return params.Edit()
+	case CoreToolNameWrite:
+		return params.Write()
+	case CoreToolNameReadText:
+		return params.ReadText()
+	case CoreToolNameGrep:
+		return params.Grep()
+	case CoreToolNameGlob:
+		return params.Glob()
+	case CoreToolNameReadMedia:
+		return params.ReadMedia()
+	case CoreToolNameSearch:
+		return params.Search()
+	case CoreToolNameTodoWrite:
+		return params.TodoWrite()
+	case CoreToolNameExitPlanMode:
+		return params.ExitPlanMode()
+	case CoreToolNameShow:
+		return params.Show()
+	case CoreToolNameTask:
+		return params.Task()
+	}
+	return params.Raw(), nil
+}
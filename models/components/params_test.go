@@ -0,0 +1,74 @@
+package components
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	t.Parallel()
+	id, event := "1", "permission"
+	tests := []struct {
+		name string
+		data string
+		want any
+	}{
+		{
+			"bash",
+			`{"id":"p1","sessionId":"s1","toolName":"Bash","action":"execute","description":"run","type":"permission","params":{"command":"rm -rf build","timeout":1000}}`,
+			&BashParams{Command: "rm -rf build", Timeout: ptr(int64(1000))},
+		},
+		{
+			"edit",
+			`{"id":"p1","sessionId":"s1","toolName":"Edit","action":"write","description":"edit","type":"permission","params":{"file_path":"/w/a.go","old_string":"a","new_string":"b","replace_all":true}}`,
+			&EditParams{FilePath: "/w/a.go", OldString: "a", NewString: "b", ReplaceAll: ptr(true)},
+		},
+		{
+			"grep flags",
+			`{"id":"p1","sessionId":"s1","toolName":"Grep","action":"read","description":"grep","type":"permission","params":{"pattern":"TODO","-i":true,"-C":2}}`,
+			&GrepParams{Pattern: "TODO", CaseInsensitive: ptr(true), Context: ptr(int64(2))},
+		},
+		{
+			"mcp tool",
+			`{"id":"p1","sessionId":"s1","toolName":"github_create_issue","action":"call","description":"issue","type":"permission","params":{"title":"bug","labels":["p1"]}}`,
+			json.RawMessage(`{"title":"bug","labels":["p1"]}`),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			decoded, ok, err := DecodeSSEEvent(&id, &event, nil, []byte(tt.data))
+			require.NoError(t, err)
+			require.True(t, ok)
+			req := decoded.SSEPermissionEvent.Data
+
+			got, err := req.ToolParams()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			out, err := json.Marshal(req)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.data, string(out), "params survive a round trip")
+		})
+	}
+
+	_, err := (&SSEPermissionEventData{ToolName: CreateToolNameStr("Bash")}).ToolParams()
+	assert.ErrorIs(t, err, ErrNoParams)
+
+	_, err = (&Params{raw: json.RawMessage(`{"command":1}`)}).Bash()
+	assert.ErrorContains(t, err, "decoding Bash params")
+
+	params, err := NewParams(WriteParams{FilePath: "/w/.env", Content: "x"})
+	require.NoError(t, err)
+	m, err := params.Map()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"file_path": "/w/.env", "content": "x"}, m)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
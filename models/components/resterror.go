@@ -1,10 +1,9 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
 )
 
 // RESTErrorType - Error type
@@ -39,6 +38,10 @@ func (e *RESTErrorType) UnmarshalJSON(data []byte) error {
 		*e = RESTErrorType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = RESTErrorType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for RESTErrorType: %v", v)
 	}
 }
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
@@ -38,6 +36,10 @@ func (e *BrowserMode) UnmarshalJSON(data []byte) error {
 		*e = BrowserMode(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = BrowserMode(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for BrowserMode: %v", v)
 	}
 }
@@ -67,6 +69,10 @@ func (e *SessionType) UnmarshalJSON(data []byte) error {
 		*e = SessionType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SessionType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SessionType: %v", v)
 	}
 }
@@ -91,6 +97,10 @@ func (e *SubagentType) UnmarshalJSON(data []byte) error {
 		*e = SubagentType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SubagentType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SubagentType: %v", v)
 	}
 }
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package components
 
 import (
@@ -74,6 +72,10 @@ func (e *SSESessionDeletedEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSESessionDeletedEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSESessionDeletedEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSESessionDeletedEventEvent: %v", v)
 	}
 }
@@ -225,6 +227,10 @@ func (e *SSESessionCreatedEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSESessionCreatedEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSESessionCreatedEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSESessionCreatedEventEvent: %v", v)
 	}
 }
@@ -394,6 +400,10 @@ func (e *SSEUserMessageCreatedEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEUserMessageCreatedEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEUserMessageCreatedEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEUserMessageCreatedEventEvent: %v", v)
 	}
 }
@@ -563,6 +573,10 @@ func (e *SSENotificationEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSENotificationEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSENotificationEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSENotificationEventEvent: %v", v)
 	}
 }
@@ -596,6 +610,10 @@ func (e *NotificationType) UnmarshalJSON(data []byte) error {
 		*e = NotificationType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = NotificationType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for NotificationType: %v", v)
 	}
 }
@@ -626,6 +644,10 @@ func (e *ResponseType) UnmarshalJSON(data []byte) error {
 		*e = ResponseType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = ResponseType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for ResponseType: %v", v)
 	}
 }
@@ -858,22 +880,32 @@ func (e *SSEPermissionEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEPermissionEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEPermissionEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEPermissionEventEvent: %v", v)
 	}
 }
 
-// Params - Additional parameters for the permission request
+// Params - Additional parameters for the permission request. The parameters
+// depend on the tool; see params.go for typed accessors.
 type Params struct {
+	raw json.RawMessage
 }
 
 func (p Params) MarshalJSON() ([]byte, error) {
-	return utils.MarshalJSON(p, "", false)
+	if len(p.raw) == 0 {
+		return []byte("{}"), nil
+	}
+	return p.raw, nil
 }
 
 func (p *Params) UnmarshalJSON(data []byte) error {
-	if err := utils.UnmarshalJSON(data, &p, "", false, nil); err != nil {
-		return err
+	if !json.Valid(data) {
+		return fmt.Errorf("invalid JSON for Params: %s", data)
 	}
+	p.raw = append(json.RawMessage(nil), data...)
 	return nil
 }
 
@@ -1087,6 +1119,10 @@ func (e *SSEToolExecutionCompleteEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEToolExecutionCompleteEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEToolExecutionCompleteEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEToolExecutionCompleteEventEvent: %v", v)
 	}
 }
@@ -1274,6 +1310,10 @@ func (e *SSEToolExecutionStartEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEToolExecutionStartEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEToolExecutionStartEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEToolExecutionStartEventEvent: %v", v)
 	}
 }
@@ -1452,6 +1492,10 @@ func (e *SSEToolUseParameterDeltaEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEToolUseParameterDeltaEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEToolUseParameterDeltaEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEToolUseParameterDeltaEventEvent: %v", v)
 	}
 }
@@ -1630,6 +1674,10 @@ func (e *SSEToolUseParameterStreamingCompleteEventEvent) UnmarshalJSON(data []by
 		*e = SSEToolUseParameterStreamingCompleteEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEToolUseParameterStreamingCompleteEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEToolUseParameterStreamingCompleteEventEvent: %v", v)
 	}
 }
@@ -1817,6 +1865,10 @@ func (e *SSEToolUseStartEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEToolUseStartEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEToolUseStartEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEToolUseStartEventEvent: %v", v)
 	}
 }
@@ -1995,6 +2047,10 @@ func (e *SSEContentEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEContentEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEContentEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEContentEventEvent: %v", v)
 	}
 }
@@ -2164,6 +2220,10 @@ func (e *SSEThinkingEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEThinkingEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEThinkingEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEThinkingEventEvent: %v", v)
 	}
 }
@@ -2333,6 +2393,10 @@ func (e *SSECompleteEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSECompleteEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSECompleteEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSECompleteEventEvent: %v", v)
 	}
 }
@@ -2529,6 +2593,10 @@ func (e *SSEErrorEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEErrorEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEErrorEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEErrorEventEvent: %v", v)
 	}
 }
@@ -2716,6 +2784,10 @@ func (e *SSEHeartbeatEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEHeartbeatEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEHeartbeatEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEHeartbeatEventEvent: %v", v)
 	}
 }
@@ -2858,6 +2930,10 @@ func (e *SSEConnectedEventEvent) UnmarshalJSON(data []byte) error {
 		*e = SSEConnectedEventEvent(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SSEConnectedEventEvent(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SSEConnectedEventEvent: %v", v)
 	}
 }
@@ -2974,6 +3050,9 @@ type SSEEventStream struct {
 	SSEUserMessageCreatedEvent                *SSEUserMessageCreatedEvent                `queryParam:"inline" union:"member"`
 	SSESessionCreatedEvent                    *SSESessionCreatedEvent                    `queryParam:"inline" union:"member"`
 	SSESessionDeletedEvent                    *SSESessionDeletedEvent                    `queryParam:"inline" union:"member"`
+	// SSEUnknownEvent is set for event types this SDK version does not know,
+	// in which case Type holds the raw event name.
+	SSEUnknownEvent *SSEUnknownEvent `queryParam:"inline" union:"member"`
 
 	Type SSEEventStreamType
 }
@@ -3328,7 +3407,14 @@ func (u *SSEEventStream) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
-	return fmt.Errorf("could not unmarshal `%s` into any supported union types for SSEEventStream", string(data))
+	sseUnknownEvent := new(SSEUnknownEvent)
+	if err := json.Unmarshal(data, sseUnknownEvent); err != nil {
+		return fmt.Errorf("could not unmarshal `%s` into any supported union types for SSEEventStream: %w", string(data), err)
+	}
+
+	u.SSEUnknownEvent = sseUnknownEvent
+	u.Type = SSEEventStreamType(dis.Event)
+	return nil
 }
 
 func (u SSEEventStream) MarshalJSON() ([]byte, error) {
@@ -3396,6 +3482,10 @@ func (u SSEEventStream) MarshalJSON() ([]byte, error) {
 		return utils.MarshalJSON(u.SSESessionDeletedEvent, "", true)
 	}
 
+	if u.SSEUnknownEvent != nil {
+		return json.Marshal(u.SSEUnknownEvent)
+	}
+
 	return nil, errors.New("could not marshal union type SSEEventStream: all fields are null")
 }
 
@@ -3434,5 +3524,6 @@ func (o SSEEventStream) GetEventEncoding(event string) (string, error) {
 	case "user_message_created":
 		return "application/json", nil
 	}
-	return "", errors.New("could not find encoding for event: " + event)
+	// Unknown events are decoded into SSEUnknownEvent.
+	return "application/json", nil
 }
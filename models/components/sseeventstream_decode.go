@@ -0,0 +1,177 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Plain variants of the event payloads. Converting to them drops the
+// generated UnmarshalJSON methods, so encoding/json decodes the fields
+// directly instead of going through the reflective union machinery.
+type (
+	sseConnectedEventDataPlain                         SSEConnectedEventData
+	sseHeartbeatEventDataPlain                         SSEHeartbeatEventData
+	sseErrorEventDataPlain                             SSEErrorEventData
+	sseCompleteEventDataPlain                          SSECompleteEventData
+	sseThinkingEventDataPlain                          SSEThinkingEventData
+	sseContentEventDataPlain                           SSEContentEventData
+	sseToolUseStartEventDataPlain                      SSEToolUseStartEventData
+	sseToolUseParameterStreamingCompleteEventDataPlain SSEToolUseParameterStreamingCompleteEventData
+	sseToolUseParameterDeltaEventDataPlain             SSEToolUseParameterDeltaEventData
+	sseToolExecutionStartEventDataPlain                SSEToolExecutionStartEventData
+	sseToolExecutionCompleteEventDataPlain             SSEToolExecutionCompleteEventData
+	ssePermissionEventDataPlain                        SSEPermissionEventData
+	sseNotificationEventDataPlain                      SSENotificationEventData
+	sseUserMessageCreatedEventDataPlain                SSEUserMessageCreatedEventData
+	sseSessionCreatedEventDataPlain                    SSESessionCreatedEventData
+	sseSessionDeletedEventDataPlain                    SSESessionDeletedEventData
+)
+
+// sseEventRequiredFields lists the required payload fields of each event, as
+// checked by the generated UnmarshalJSON methods.
+var sseEventRequiredFields = map[string][]string{
+	"connected":                             {"sessionId"},
+	"heartbeat":                             {"type"},
+	"error":                                 {"error"},
+	"complete":                              {"done", "type"},
+	"thinking":                              {"content", "type"},
+	"content":                               {"content", "type"},
+	"tool_use_start":                        {"id", "name", "type"},
+	"tool_use_parameter_streaming_complete": {"id", "input", "name", "type"},
+	"tool_use_parameter_delta":              {"input", "toolCallId", "type"},
+	"tool_execution_start":                  {"progress", "toolCallId", "toolName", "type"},
+	"tool_execution_complete":               {"progress", "success", "toolCallId", "toolName", "type"},
+	"permission":                            {"action", "description", "id", "sessionId", "toolName", "type"},
+	"notification":                          {"createdAt", "id", "message", "notificationType", "responseType", "sessionId", "timeout", "title", "type"},
+	"user_message_created":                  {"content", "messageId", "type"},
+	"session_created":                       {"createdAt", "sessionId", "title", "type"},
+	"session_deleted":                       {"sessionId", "type"},
+}
+
+// DecodeSSEEvent decodes a server-sent event straight into the union member
+// named by event, without building a JSON envelope or trying union
+// candidates. Like UnmarshalJSON, it reports payloads missing a required
+// field. It returns false for events it does not handle, such as unknown
+// event types or events without an ID, which should be decoded through
+// UnmarshalJSON. data is not retained.
+func DecodeSSEEvent(id, event *string, retry *int64, data []byte) (SSEEventStream, bool, error) {
+	if id == nil || event == nil {
+		return SSEEventStream{}, false, nil
+	}
+
+	var u SSEEventStream
+	var err error
+
+	switch *event {
+	case "connected":
+		sseConnectedEvent := &SSEConnectedEvent{Event: SSEConnectedEventEventConnected, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseConnectedEventDataPlain)(&sseConnectedEvent.Data))
+		u.SSEConnectedEvent = sseConnectedEvent
+		u.Type = SSEEventStreamTypeConnected
+	case "heartbeat":
+		sseHeartbeatEvent := &SSEHeartbeatEvent{Event: SSEHeartbeatEventEventHeartbeat, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseHeartbeatEventDataPlain)(&sseHeartbeatEvent.Data))
+		u.SSEHeartbeatEvent = sseHeartbeatEvent
+		u.Type = SSEEventStreamTypeHeartbeat
+	case "error":
+		sseErrorEvent := &SSEErrorEvent{Event: SSEErrorEventEventError, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseErrorEventDataPlain)(&sseErrorEvent.Data))
+		u.SSEErrorEvent = sseErrorEvent
+		u.Type = SSEEventStreamTypeError
+	case "complete":
+		sseCompleteEvent := &SSECompleteEvent{Event: SSECompleteEventEventComplete, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseCompleteEventDataPlain)(&sseCompleteEvent.Data))
+		u.SSECompleteEvent = sseCompleteEvent
+		u.Type = SSEEventStreamTypeComplete
+	case "thinking":
+		sseThinkingEvent := &SSEThinkingEvent{Event: SSEThinkingEventEventThinking, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseThinkingEventDataPlain)(&sseThinkingEvent.Data))
+		u.SSEThinkingEvent = sseThinkingEvent
+		u.Type = SSEEventStreamTypeThinking
+	case "content":
+		sseContentEvent := &SSEContentEvent{Event: SSEContentEventEventContent, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseContentEventDataPlain)(&sseContentEvent.Data))
+		u.SSEContentEvent = sseContentEvent
+		u.Type = SSEEventStreamTypeContent
+	case "tool_use_start":
+		sseToolUseStartEvent := &SSEToolUseStartEvent{Event: SSEToolUseStartEventEventToolUseStart, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseToolUseStartEventDataPlain)(&sseToolUseStartEvent.Data))
+		u.SSEToolUseStartEvent = sseToolUseStartEvent
+		u.Type = SSEEventStreamTypeToolUseStart
+	case "tool_use_parameter_streaming_complete":
+		sseToolUseParameterStreamingCompleteEvent := &SSEToolUseParameterStreamingCompleteEvent{Event: SSEToolUseParameterStreamingCompleteEventEventToolUseParameterStreamingComplete, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseToolUseParameterStreamingCompleteEventDataPlain)(&sseToolUseParameterStreamingCompleteEvent.Data))
+		u.SSEToolUseParameterStreamingCompleteEvent = sseToolUseParameterStreamingCompleteEvent
+		u.Type = SSEEventStreamTypeToolUseParameterStreamingComplete
+	case "tool_use_parameter_delta":
+		sseToolUseParameterDeltaEvent := &SSEToolUseParameterDeltaEvent{Event: SSEToolUseParameterDeltaEventEventToolUseParameterDelta, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseToolUseParameterDeltaEventDataPlain)(&sseToolUseParameterDeltaEvent.Data))
+		u.SSEToolUseParameterDeltaEvent = sseToolUseParameterDeltaEvent
+		u.Type = SSEEventStreamTypeToolUseParameterDelta
+	case "tool_execution_start":
+		sseToolExecutionStartEvent := &SSEToolExecutionStartEvent{Event: SSEToolExecutionStartEventEventToolExecutionStart, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseToolExecutionStartEventDataPlain)(&sseToolExecutionStartEvent.Data))
+		u.SSEToolExecutionStartEvent = sseToolExecutionStartEvent
+		u.Type = SSEEventStreamTypeToolExecutionStart
+	case "tool_execution_complete":
+		sseToolExecutionCompleteEvent := &SSEToolExecutionCompleteEvent{Event: SSEToolExecutionCompleteEventEventToolExecutionComplete, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseToolExecutionCompleteEventDataPlain)(&sseToolExecutionCompleteEvent.Data))
+		u.SSEToolExecutionCompleteEvent = sseToolExecutionCompleteEvent
+		u.Type = SSEEventStreamTypeToolExecutionComplete
+	case "permission":
+		ssePermissionEvent := &SSEPermissionEvent{Event: SSEPermissionEventEventPermission, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*ssePermissionEventDataPlain)(&ssePermissionEvent.Data))
+		u.SSEPermissionEvent = ssePermissionEvent
+		u.Type = SSEEventStreamTypePermission
+	case "notification":
+		sseNotificationEvent := &SSENotificationEvent{Event: SSENotificationEventEventNotification, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseNotificationEventDataPlain)(&sseNotificationEvent.Data))
+		u.SSENotificationEvent = sseNotificationEvent
+		u.Type = SSEEventStreamTypeNotification
+	case "user_message_created":
+		sseUserMessageCreatedEvent := &SSEUserMessageCreatedEvent{Event: SSEUserMessageCreatedEventEventUserMessageCreated, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseUserMessageCreatedEventDataPlain)(&sseUserMessageCreatedEvent.Data))
+		u.SSEUserMessageCreatedEvent = sseUserMessageCreatedEvent
+		u.Type = SSEEventStreamTypeUserMessageCreated
+	case "session_created":
+		sseSessionCreatedEvent := &SSESessionCreatedEvent{Event: SSESessionCreatedEventEventSessionCreated, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseSessionCreatedEventDataPlain)(&sseSessionCreatedEvent.Data))
+		u.SSESessionCreatedEvent = sseSessionCreatedEvent
+		u.Type = SSEEventStreamTypeSessionCreated
+	case "session_deleted":
+		sseSessionDeletedEvent := &SSESessionDeletedEvent{Event: SSESessionDeletedEventEventSessionDeleted, ID: *id, Retry: retry}
+		err = json.Unmarshal(data, (*sseSessionDeletedEventDataPlain)(&sseSessionDeletedEvent.Data))
+		u.SSESessionDeletedEvent = sseSessionDeletedEvent
+		u.Type = SSEEventStreamTypeSessionDeleted
+	default:
+		return SSEEventStream{}, false, nil
+	}
+
+	if err == nil {
+		err = checkRequiredFields(data, sseEventRequiredFields[*event])
+	}
+	if err != nil {
+		return SSEEventStream{}, true, fmt.Errorf("could not decode `%s` event: %w", *event, err)
+	}
+	return u, true, nil
+}
+
+// checkRequiredFields reports the fields missing from the JSON object data.
+func checkRequiredFields(data []byte, required []string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
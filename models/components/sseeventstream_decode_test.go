@@ -0,0 +1,170 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sampleSSEEvents = []struct {
+	event string
+	data  string
+}{
+	{"connected", `{"sessionId":"s1"}`},
+	{"heartbeat", `{"type":"heartbeat"}`},
+	{"error", `{"error":"rate limited","type":"llm","attempt":1,"maxAttempts":3,"retryAfter":500}`},
+	{"complete", `{"done":true,"type":"complete","messageId":"m1","content":"hi","reasoningDuration":12}`},
+	{"thinking", `{"content":"hmm","type":"thinking"}`},
+	{"content", `{"content":"Hello, world","type":"content","assistantMessageId":"m1"}`},
+	{"tool_use_start", `{"id":"t1","name":"Bash","type":"tool_use_start"}`},
+	{"tool_use_parameter_delta", `{"toolCallId":"t1","input":"{\"command\":","type":"tool_use_parameter_delta"}`},
+	{"tool_use_parameter_streaming_complete", `{"id":"t1","name":"github_search","input":"{}","type":"tool_use_parameter_streaming_complete"}`},
+	{"tool_execution_start", `{"toolCallId":"t1","toolName":"Bash","progress":"running","type":"tool_execution_start"}`},
+	{"tool_execution_complete", `{"toolCallId":"t1","toolName":"Bash","progress":"done","success":true,"type":"tool_execution_complete","parentToolCallId":"t0"}`},
+	{"permission", `{"action":"run","description":"Run ls","id":"p1","sessionId":"s1","toolName":"Bash","type":"permission","path":"/tmp","params":{"command":"ls -la /tmp","timeout":5000}}`},
+	{"notification", `{"id":"n1","message":"Pick one","notificationType":"question","responseType":"choice","options":["a","b"],"sessionId":"s1","title":"Q","type":"notification","createdAt":1700000000,"timeout":30}`},
+	{"user_message_created", `{"messageId":"u1","content":"hi","sessionId":"s1","type":"user_message_created"}`},
+	{"session_created", `{"createdAt":1700000000,"sessionId":"s2","title":"New","type":"session_created"}`},
+	{"session_deleted", `{"sessionId":"s2","type":"session_deleted"}`},
+}
+
+func decodeSSEEnvelope(id, event, data string) (SSEEventStream, error) {
+	var e SSEEventStream
+	envelope := fmt.Sprintf(`{"id":%q,"event":%q,"retry":1000,"data":%s}`, id, event, data)
+	err := utils.UnmarshalJsonFromResponseBody(bytes.NewBufferString(envelope), &e, "")
+	return e, err
+}
+
+func TestDecodeSSEEvent_MatchesUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+	id, retry := "42", int64(1000)
+	for _, sample := range sampleSSEEvents {
+		sample := sample
+		t.Run(sample.event, func(t *testing.T) {
+			t.Parallel()
+			want, err := decodeSSEEnvelope(id, sample.event, sample.data)
+			require.NoError(t, err)
+
+			got, ok, err := DecodeSSEEvent(&id, &sample.event, &retry, []byte(sample.data))
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecodeSSEEvent_MalformedMatchesUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+	id := "42"
+	tests := []struct {
+		name  string
+		event string
+		data  string
+	}{
+		{"permission without id", "permission", `{"action":"run","description":"Run ls","sessionId":"s1","toolName":"Bash","type":"permission"}`},
+		{"permission empty", "permission", `{}`},
+		{"notification without response type", "notification", `{"id":"n1","message":"Pick one","notificationType":"question","sessionId":"s1","title":"Q","type":"notification","createdAt":1700000000,"timeout":30}`},
+		{"content without content", "content", `{"type":"content"}`},
+		{"tool execution without success", "tool_execution_complete", `{"toolCallId":"t1","toolName":"Bash","progress":"done","type":"tool_execution_complete"}`},
+		{"error without message", "error", `{"type":"llm"}`},
+		{"not an object", "content", `["content"]`},
+		{"wrong field type", "content", `{"content":1,"type":"content"}`},
+		{"invalid json", "complete", `{"done":`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, wantErr := decodeSSEEnvelope(id, tt.event, tt.data)
+			require.Error(t, wantErr)
+
+			_, ok, err := DecodeSSEEvent(&id, &tt.event, nil, []byte(tt.data))
+			assert.True(t, ok)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeSSEEvent_DroppedFieldMatchesUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+	id := "42"
+	for _, sample := range sampleSSEEvents {
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(sample.data), &fields))
+		for name := range fields {
+			partial := make(map[string]json.RawMessage, len(fields))
+			for k, v := range fields {
+				if k != name {
+					partial[k] = v
+				}
+			}
+			data, err := json.Marshal(partial)
+			require.NoError(t, err)
+
+			want, wantErr := decodeSSEEnvelope(id, sample.event, string(data))
+			got, ok, err := DecodeSSEEvent(&id, &sample.event, nil, data)
+			require.True(t, ok)
+			if wantErr != nil {
+				assert.Error(t, err, "%s without %s", sample.event, name)
+				continue
+			}
+			require.NoError(t, err, "%s without %s", sample.event, name)
+			assert.Equal(t, want.Type, got.Type)
+		}
+	}
+}
+
+func TestDecodeSSEEvent_MissingFields(t *testing.T) {
+	t.Parallel()
+	id, event := "42", "permission"
+	_, _, err := DecodeSSEEvent(&id, &event, nil, []byte(`{"action":"run","description":"Run ls","toolName":"Bash","type":"permission"}`))
+	assert.EqualError(t, err, "could not decode `permission` event: missing required fields: id, sessionId")
+}
+
+func TestDecodeSSEEvent_Fallback(t *testing.T) {
+	t.Parallel()
+	id, event := "1", "plan_updated"
+	_, ok, err := DecodeSSEEvent(&id, &event, nil, []byte(`{}`))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	content := "content"
+	_, ok, _ = DecodeSSEEvent(nil, &content, nil, []byte(`{}`))
+	assert.False(t, ok)
+}
+
+func benchmarkSSEDecode(b *testing.B, event, data string, direct bool) {
+	id := "42"
+	payload := []byte(data)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if direct {
+			_, _, err = DecodeSSEEvent(&id, &event, nil, payload)
+		} else {
+			_, err = decodeSSEEnvelope(id, event, data)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeSSEEvent(b *testing.B) {
+	for _, event := range []string{"content", "tool_use_parameter_delta", "tool_execution_complete"} {
+		var data string
+		for _, sample := range sampleSSEEvents {
+			if sample.event == event {
+				data = sample.data
+			}
+		}
+		b.Run(event+"/reflection", func(b *testing.B) { benchmarkSSEDecode(b, event, data, false) })
+		b.Run(event+"/direct", func(b *testing.B) { benchmarkSSEDecode(b, event, data, true) })
+	}
+}
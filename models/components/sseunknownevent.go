@@ -0,0 +1,55 @@
+package components
+
+import (
+	"encoding/json"
+)
+
+// SSEUnknownEvent holds an event whose type this version of the SDK does not
+// recognize, so that newer servers do not break older clients.
+type SSEUnknownEvent struct {
+	// Event type identifier as sent by the server
+	Event string `json:"event"`
+	// Unique sequential event identifier for ordering and reconnection
+	ID string `json:"id,omitempty"`
+	// Client retry interval in milliseconds
+	Retry *int64 `json:"retry,omitempty"`
+	// Undecoded event payload
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func (s *SSEUnknownEvent) GetEvent() string {
+	if s == nil {
+		return ""
+	}
+	return s.Event
+}
+
+func (s *SSEUnknownEvent) GetID() string {
+	if s == nil {
+		return ""
+	}
+	return s.ID
+}
+
+func (s *SSEUnknownEvent) GetRetry() *int64 {
+	if s == nil {
+		return nil
+	}
+	return s.Retry
+}
+
+func (s *SSEUnknownEvent) GetData() json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	return s.Data
+}
+
+// CreateSSEEventStreamUnknown wraps an unrecognized event. The union's Type is
+// set to the raw event name.
+func CreateSSEEventStreamUnknown(unknown SSEUnknownEvent) SSEEventStream {
+	return SSEEventStream{
+		SSEUnknownEvent: &unknown,
+		Type:            SSEEventStreamType(unknown.Event),
+	}
+}
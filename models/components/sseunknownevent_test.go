@@ -0,0 +1,40 @@
+package components
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEEventStream_UnknownEvent(t *testing.T) {
+	t.Parallel()
+	raw := `{"id":"7","event":"plan_updated","data":{"steps":["a","b"]}}`
+
+	var e SSEEventStream
+	require.NoError(t, json.Unmarshal([]byte(raw), &e))
+	require.NotNil(t, e.SSEUnknownEvent)
+	assert.Equal(t, SSEEventStreamType("plan_updated"), e.Type)
+	assert.Equal(t, "7", e.SSEUnknownEvent.ID)
+	assert.JSONEq(t, `{"steps":["a","b"]}`, string(e.SSEUnknownEvent.Data))
+
+	out, err := json.Marshal(e)
+	require.NoError(t, err)
+	assert.JSONEq(t, raw, string(out))
+
+	encoding, err := e.GetEventEncoding("plan_updated")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", encoding)
+}
+
+func TestNotificationType_Lenient(t *testing.T) {
+	var v NotificationType
+	require.Error(t, json.Unmarshal([]byte(`"success"`), &v))
+
+	utils.SetLenientEnums(true)
+	defer utils.SetLenientEnums(false)
+	require.NoError(t, json.Unmarshal([]byte(`"success"`), &v))
+	assert.Equal(t, NotificationType("success"), v)
+}
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package operations
 
 import (
@@ -38,6 +36,10 @@ func (e *BrowserMode) UnmarshalJSON(data []byte) error {
 		*e = BrowserMode(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = BrowserMode(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for BrowserMode: %v", v)
 	}
 }
@@ -71,6 +73,10 @@ func (e *PromptMode) UnmarshalJSON(data []byte) error {
 		*e = PromptMode(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = PromptMode(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for PromptMode: %v", v)
 	}
 }
@@ -95,6 +101,10 @@ func (e *SessionType) UnmarshalJSON(data []byte) error {
 		*e = SessionType(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = SessionType(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for SessionType: %v", v)
 	}
 }
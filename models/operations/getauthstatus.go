@@ -1,10 +1,9 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package operations
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
 	"github.com/recreate-run/mix-go-sdk/models/components"
 )
 
@@ -34,6 +33,10 @@ func (e *GetAuthStatusAuthMethod) UnmarshalJSON(data []byte) error {
 		*e = GetAuthStatusAuthMethod(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = GetAuthStatusAuthMethod(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for GetAuthStatusAuthMethod: %v", v)
 	}
 }
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package operations
 
 import (
@@ -42,6 +40,10 @@ func (e *ProvidersStatus) UnmarshalJSON(data []byte) error {
 		*e = ProvidersStatus(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = ProvidersStatus(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for ProvidersStatus: %v", v)
 	}
 }
@@ -140,6 +142,10 @@ func (e *Status) UnmarshalJSON(data []byte) error {
 		*e = Status(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = Status(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for Status: %v", v)
 	}
 }
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package operations
 
 import (
@@ -39,6 +37,10 @@ func (e *ThinkingLevel) UnmarshalJSON(data []byte) error {
 		*e = ThinkingLevel(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = ThinkingLevel(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for ThinkingLevel: %v", v)
 	}
 }
@@ -1,10 +1,9 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package operations
 
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
 	"github.com/recreate-run/mix-go-sdk/models/components"
 )
 
@@ -40,6 +39,10 @@ func (e *Provider) UnmarshalJSON(data []byte) error {
 		*e = Provider(v)
 		return nil
 	default:
+		if utils.LenientEnums() {
+			*e = Provider(v)
+			return nil
+		}
 		return fmt.Errorf("invalid value for Provider: %v", v)
 	}
 }
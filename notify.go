@@ -0,0 +1,256 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+// ErrInvalidNotificationResponse is returned for a response that does not
+// fit the notification it answers, such as text for a choice or a choice that
+// was not offered.
+var ErrInvalidNotificationResponse = errors.New("invalid notification response")
+
+// AcknowledgeResponse returns the response acknowledging n, which must expect
+// an acknowledgement.
+func AcknowledgeResponse(n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+	res := &operations.RespondToNotificationRequestBody{Type: operations.TypeAcknowledge}
+	return res, ValidateNotificationResponse(n, *res)
+}
+
+// TextResponse returns the response answering n with text, which must not be
+// blank. n must expect a text response.
+func TextResponse(n components.SSENotificationEventData, text string) (*operations.RespondToNotificationRequestBody, error) {
+	res := &operations.RespondToNotificationRequestBody{Type: operations.TypeText, Value: &text}
+	return res, ValidateNotificationResponse(n, *res)
+}
+
+// ChoiceResponse returns the response picking choice, which must be one of
+// the Choices of n.
+func ChoiceResponse(n components.SSENotificationEventData, choice string) (*operations.RespondToNotificationRequestBody, error) {
+	res := &operations.RespondToNotificationRequestBody{Type: operations.TypeChoice, Value: &choice}
+	return res, ValidateNotificationResponse(n, *res)
+}
+
+// ValidateNotificationResponse checks that res has the response type n
+// expects and a value it accepts. Errors wrap ErrInvalidNotificationResponse.
+func ValidateNotificationResponse(n components.SSENotificationEventData, res operations.RespondToNotificationRequestBody) error {
+	if string(res.Type) != string(n.ResponseType) {
+		return fmt.Errorf("%w: notification %s expects %s, got %s", ErrInvalidNotificationResponse, n.ID, n.ResponseType, res.Type)
+	}
+
+	switch res.Type {
+	case operations.TypeAcknowledge:
+	case operations.TypeText:
+		if res.Value == nil || strings.TrimSpace(*res.Value) == "" {
+			return fmt.Errorf("%w: notification %s expects non-empty text", ErrInvalidNotificationResponse, n.ID)
+		}
+	case operations.TypeChoice:
+		if len(n.Choices) == 0 {
+			return fmt.Errorf("%w: notification %s offers no choices", ErrInvalidNotificationResponse, n.ID)
+		}
+		if res.Value == nil || !slices.Contains(n.Choices, *res.Value) {
+			var got string
+			if res.Value != nil {
+				got = *res.Value
+			}
+			return fmt.Errorf("%w: %q is not one of the choices of notification %s (%s)", ErrInvalidNotificationResponse, got, n.ID, strings.Join(n.Choices, ", "))
+		}
+	default:
+		return fmt.Errorf("%w: unknown response type %s", ErrInvalidNotificationResponse, res.Type)
+	}
+	return nil
+}
+
+// Acknowledge acknowledges n, which must expect an acknowledgement.
+func (s *Notifications) Acknowledge(ctx context.Context, n components.SSENotificationEventData, opts ...operations.Option) (*operations.RespondToNotificationResponse, error) {
+	res, err := AcknowledgeResponse(n)
+	if err != nil {
+		return nil, err
+	}
+	return s.RespondToNotification(ctx, n.ID, *res, opts...)
+}
+
+// AnswerText answers n, which must expect a text response, with text.
+func (s *Notifications) AnswerText(ctx context.Context, n components.SSENotificationEventData, text string, opts ...operations.Option) (*operations.RespondToNotificationResponse, error) {
+	res, err := TextResponse(n, text)
+	if err != nil {
+		return nil, err
+	}
+	return s.RespondToNotification(ctx, n.ID, *res, opts...)
+}
+
+// Choose answers n with one of its Choices.
+func (s *Notifications) Choose(ctx context.Context, n components.SSENotificationEventData, choice string, opts ...operations.Option) (*operations.RespondToNotificationResponse, error) {
+	res, err := ChoiceResponse(n, choice)
+	if err != nil {
+		return nil, err
+	}
+	return s.RespondToNotification(ctx, n.ID, *res, opts...)
+}
+
+type routerOptions struct {
+	autoAcknowledge bool
+	fallback        NotificationHandler
+}
+
+type RouterOption func(*routerOptions)
+
+// WithoutAutoAcknowledge leaves info notifications that no handler answered
+// unanswered.
+func WithoutAutoAcknowledge() RouterOption {
+	return func(o *routerOptions) {
+		o.autoAcknowledge = false
+	}
+}
+
+// WithFallbackHandler registers a handler for notification types without
+// their own handler.
+func WithFallbackHandler(handler NotificationHandler) RouterOption {
+	return func(o *routerOptions) {
+		o.fallback = handler
+	}
+}
+
+// NotificationRouter routes notifications to handlers by NotificationType and
+// sends their responses through Notifications.RespondToNotification once
+// validated against the notification. Info notifications expecting an
+// acknowledgement are acknowledged when no handler answers them, so handlers
+// for info can simply observe them. Handlers run with a deadline of the
+// notification's CreatedAt plus its Timeout. Each notification is answered once, however
+// many times its event is delivered. It is safe for concurrent use.
+type NotificationRouter struct {
+	notifications *Notifications
+	opts          routerOptions
+
+	mu       sync.Mutex
+	handlers map[components.NotificationType]NotificationHandler
+	answered map[string]struct{}
+}
+
+// NewRouter creates a router without handlers.
+func (s *Notifications) NewRouter(opts ...RouterOption) *NotificationRouter {
+	o := routerOptions{autoAcknowledge: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &NotificationRouter{
+		notifications: s,
+		opts:          o,
+		handlers:      map[components.NotificationType]NotificationHandler{},
+		answered:      map[string]struct{}{},
+	}
+}
+
+// On registers the handler for a notification type, replacing any previous
+// one. A handler returning a nil response leaves the notification unanswered,
+// apart from auto-acknowledged info notifications.
+func (r *NotificationRouter) On(t components.NotificationType, handler NotificationHandler) *NotificationRouter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[t] = handler
+	return r
+}
+
+// Respond routes n to its handler and returns the validated response, or nil
+// when n is left unanswered. It does not send the response.
+func (r *NotificationRouter) Respond(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+	r.mu.Lock()
+	handler, ok := r.handlers[n.NotificationType]
+	r.mu.Unlock()
+	if !ok {
+		handler = r.opts.fallback
+	}
+
+	var res *operations.RespondToNotificationRequestBody
+	if handler != nil {
+		handlerCtx := ctx
+		if deadline, ok := notificationDeadline(n); ok {
+			var cancel context.CancelFunc
+			handlerCtx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		var err error
+		if res, err = handler(handlerCtx, n); err != nil {
+			return nil, fmt.Errorf("notification handler: %w", err)
+		}
+	}
+
+	if res == nil {
+		if r.opts.autoAcknowledge && n.NotificationType == components.NotificationTypeInfo && n.ResponseType == components.ResponseTypeAcknowledge {
+			return AcknowledgeResponse(n)
+		}
+		return nil, nil
+	}
+	if err := ValidateNotificationResponse(n, *res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// notificationDeadline returns when n expires: Timeout seconds after it was
+// created, so that a notification delivered late or again after a reconnect
+// is not given its whole timeout anew. Notifications without a creation time
+// expire Timeout seconds from now.
+func notificationDeadline(n components.SSENotificationEventData) (time.Time, bool) {
+	if n.Timeout <= 0 {
+		return time.Time{}, false
+	}
+	timeout := time.Duration(n.Timeout) * time.Second
+	if n.CreatedAt <= 0 {
+		return time.Now().Add(timeout), true
+	}
+	return time.Unix(n.CreatedAt, 0).Add(timeout), true
+}
+
+// Handle answers the notification carried by a notification event. Other
+// events and notifications already answered by the router are ignored.
+func (r *NotificationRouter) Handle(ctx context.Context, event *components.SSEEventStream) error {
+	if event == nil || event.SSENotificationEvent == nil {
+		return nil
+	}
+	n := event.SSENotificationEvent.Data
+
+	r.mu.Lock()
+	_, done := r.answered[n.ID]
+	r.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	res, err := r.Respond(ctx, n)
+	if err != nil || res == nil {
+		return err
+	}
+	if _, err := r.notifications.RespondToNotification(ctx, n.ID, *res); err != nil {
+		return fmt.Errorf("answering notification %s: %w", n.ID, err)
+	}
+
+	r.mu.Lock()
+	r.answered[n.ID] = struct{}{}
+	r.mu.Unlock()
+	return nil
+}
+
+// Run answers notifications from src until the stream ends, ctx is done, or
+// answering a notification fails.
+func (r *NotificationRouter) Run(ctx context.Context, src events.Source) error {
+	return events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		return r.Handle(ctx, event)
+	})
+}
+
+// NotificationHandler adapts the router for Messages.SendMessageAndWait.
+func (r *NotificationRouter) NotificationHandler() NotificationHandler {
+	return r.Respond
+}
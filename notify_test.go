@@ -0,0 +1,224 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+func notification(id string, t components.NotificationType, rt components.ResponseType, choices ...string) components.SSENotificationEventData {
+	return components.SSENotificationEventData{
+		ID:               id,
+		SessionID:        "s1",
+		NotificationType: t,
+		ResponseType:     rt,
+		Choices:          choices,
+		Type:             "notification",
+	}
+}
+
+func TestNotificationResponses(t *testing.T) {
+	t.Parallel()
+	ack := notification("n1", components.NotificationTypeInfo, components.ResponseTypeAcknowledge)
+	text := notification("n2", components.NotificationTypeQuestion, components.ResponseTypeText)
+	choice := notification("n3", components.NotificationTypeQuestion, components.ResponseTypeChoice, "red", "blue")
+	noChoices := notification("n4", components.NotificationTypeQuestion, components.ResponseTypeChoice)
+
+	tests := []struct {
+		name    string
+		respond func() (*operations.RespondToNotificationRequestBody, error)
+		wantErr string
+	}{
+		{"acknowledge", func() (*operations.RespondToNotificationRequestBody, error) { return AcknowledgeResponse(ack) }, ""},
+		{"acknowledge question", func() (*operations.RespondToNotificationRequestBody, error) { return AcknowledgeResponse(text) }, "notification n2 expects text, got acknowledge"},
+		{"text", func() (*operations.RespondToNotificationRequestBody, error) { return TextResponse(text, "42") }, ""},
+		{"blank text", func() (*operations.RespondToNotificationRequestBody, error) { return TextResponse(text, " \n") }, "notification n2 expects non-empty text"},
+		{"text for choice", func() (*operations.RespondToNotificationRequestBody, error) { return TextResponse(choice, "red") }, "notification n3 expects choice, got text"},
+		{"choice", func() (*operations.RespondToNotificationRequestBody, error) { return ChoiceResponse(choice, "blue") }, ""},
+		{"unknown choice", func() (*operations.RespondToNotificationRequestBody, error) { return ChoiceResponse(choice, "green") }, `"green" is not one of the choices of notification n3 (red, blue)`},
+		{"no choices", func() (*operations.RespondToNotificationRequestBody, error) { return ChoiceResponse(noChoices, "red") }, "notification n4 offers no choices"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			res, err := tt.respond()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.NotNil(t, res)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidNotificationResponse)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNotificationRouter_Respond(t *testing.T) {
+	t.Parallel()
+	boom := errors.New("boom")
+	answer := func(value string) NotificationHandler {
+		return func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+			return TextResponse(n, value)
+		}
+	}
+	observe := func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+		return nil, nil
+	}
+
+	info := notification("n1", components.NotificationTypeInfo, components.ResponseTypeAcknowledge)
+	question := notification("n2", components.NotificationTypeQuestion, components.ResponseTypeText)
+	warning := notification("n3", components.NotificationTypeWarning, components.ResponseTypeText)
+	choice := notification("n4", components.NotificationTypeQuestion, components.ResponseTypeChoice, "red")
+
+	tests := []struct {
+		name     string
+		opts     []RouterOption
+		handlers map[components.NotificationType]NotificationHandler
+		n        components.SSENotificationEventData
+		want     *operations.RespondToNotificationRequestBody
+		wantErr  error
+	}{
+		{
+			name: "handler",
+			handlers: map[components.NotificationType]NotificationHandler{
+				components.NotificationTypeQuestion: answer("yes"),
+			},
+			n:    question,
+			want: &operations.RespondToNotificationRequestBody{Type: operations.TypeText, Value: String("yes")},
+		},
+		{
+			name: "auto-acknowledge without handler",
+			n:    info,
+			want: &operations.RespondToNotificationRequestBody{Type: operations.TypeAcknowledge},
+		},
+		{
+			name: "auto-acknowledge after observing",
+			handlers: map[components.NotificationType]NotificationHandler{
+				components.NotificationTypeInfo: observe,
+			},
+			n:    info,
+			want: &operations.RespondToNotificationRequestBody{Type: operations.TypeAcknowledge},
+		},
+		{
+			name: "without auto-acknowledge",
+			opts: []RouterOption{WithoutAutoAcknowledge()},
+			n:    info,
+		},
+		{
+			name: "no handler",
+			n:    question,
+		},
+		{
+			name: "fallback",
+			opts: []RouterOption{WithFallbackHandler(answer("fallback"))},
+			handlers: map[components.NotificationType]NotificationHandler{
+				components.NotificationTypeQuestion: answer("yes"),
+			},
+			n:    warning,
+			want: &operations.RespondToNotificationRequestBody{Type: operations.TypeText, Value: String("fallback")},
+		},
+		{
+			name: "invalid response",
+			handlers: map[components.NotificationType]NotificationHandler{
+				components.NotificationTypeQuestion: func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+					return &operations.RespondToNotificationRequestBody{Type: operations.TypeChoice, Value: String("blue")}, nil
+				},
+			},
+			n:       choice,
+			wantErr: ErrInvalidNotificationResponse,
+		},
+		{
+			name: "handler error",
+			handlers: map[components.NotificationType]NotificationHandler{
+				components.NotificationTypeQuestion: func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+					return nil, boom
+				},
+			},
+			n:       question,
+			wantErr: boom,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := New("http://localhost").Notifications.NewRouter(tt.opts...)
+			for nt, handler := range tt.handlers {
+				r.On(nt, handler)
+			}
+
+			res, err := r.Respond(context.Background(), tt.n)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestNotificationRouter_Deadline(t *testing.T) {
+	t.Parallel()
+	var deadlines []time.Time
+	r := New("http://localhost").Notifications.NewRouter().
+		On(components.NotificationTypeQuestion, func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			deadlines = append(deadlines, deadline)
+			return TextResponse(n, "yes")
+		})
+
+	// Delivered again a minute after it was created.
+	n := notification("n1", components.NotificationTypeQuestion, components.ResponseTypeText)
+	created := time.Now().Add(-time.Minute).Unix()
+	n.CreatedAt = created
+	n.Timeout = 90
+	_, err := r.Respond(context.Background(), n)
+	require.NoError(t, err)
+
+	// Without a creation time the timeout starts now.
+	n.CreatedAt = 0
+	before := time.Now()
+	_, err = r.Respond(context.Background(), n)
+	require.NoError(t, err)
+
+	require.Len(t, deadlines, 2)
+	assert.True(t, time.Unix(created, 0).Add(90*time.Second).Equal(deadlines[0]), "CreatedAt plus Timeout")
+	assert.WithinRange(t, deadlines[1], before.Add(90*time.Second), time.Now().Add(90*time.Second))
+}
+
+func TestNotificationRouter_Handle(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, nil)
+
+	var calls int
+	r := client.Notifications.NewRouter().
+		On(components.NotificationTypeQuestion, func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error) {
+			calls++
+			return TextResponse(n, "yes")
+		})
+
+	ctx := context.Background()
+	question := components.CreateSSEEventStreamNotification(components.SSENotificationEvent{
+		Data: notification("n1", components.NotificationTypeQuestion, components.ResponseTypeText),
+	})
+	unanswered := components.CreateSSEEventStreamNotification(components.SSENotificationEvent{
+		Data: notification("n2", components.NotificationTypeWarning, components.ResponseTypeText),
+	})
+	content := components.CreateSSEEventStreamContent(components.SSEContentEvent{})
+
+	for _, event := range []*components.SSEEventStream{&question, &unanswered, &question, &content, nil} {
+		require.NoError(t, r.Handle(ctx, event))
+	}
+	assert.Equal(t, 1, calls, "a redelivered notification is answered once")
+	assert.Equal(t, []string{`notification:n1 {"type":"text","value":"yes"}`}, srv.received())
+}
@@ -0,0 +1,41 @@
+package mix
+
+import (
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
+)
+
+// WithMaxEventSize sets the largest single server-sent event, in bytes, that
+// event streams will accept. A size of zero or less removes the limit.
+func WithMaxEventSize(size int) SDKOption {
+	return func(sdk *Mix) {
+		sdk.sdkConfiguration.MaxEventSize = &size
+	}
+}
+
+// WithStreamIdleTimeout fails event streams with stream.ErrStalled when no
+// event, heartbeat or keep-alive arrives within d, instead of blocking on a
+// half-open connection. Subscriptions reconnect when this happens.
+func WithStreamIdleTimeout(d time.Duration) SDKOption {
+	return func(sdk *Mix) {
+		sdk.sdkConfiguration.StreamIdleTimeout = &d
+	}
+}
+
+// WithCancelOnContextDone makes SendMessageAndWait and Subscriptions call
+// Messages.CancelSessionProcessing when their context is canceled or its
+// deadline passes, so an abandoned turn stops running tools and using tokens.
+// The cancel is best-effort and reported through an *apierrors.CancelError.
+func WithCancelOnContextDone() SDKOption {
+	return func(sdk *Mix) {
+		sdk.sdkConfiguration.CancelOnContextDone = true
+	}
+}
+
+// SetLenientEnums makes enum fields in responses and events accept values this
+// SDK version does not know, keeping the raw string instead of failing to
+// decode. The setting is process-wide and off by default.
+func SetLenientEnums(enabled bool) {
+	utils.SetLenientEnums(enabled)
+}
@@ -0,0 +1,248 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	mix "github.com/recreate-run/mix-go-sdk"
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// ErrUnanswered is returned by Engine.Decide for requests the policy defers
+// with Ask when the engine has no asker.
+var ErrUnanswered = errors.New("policy: request needs an answer but no asker is configured")
+
+// Answer is an asker's response to a request.
+type Answer struct {
+	// Allow or Deny
+	Decision Decision
+	// Apply the decision to later requests in the session for the same tool,
+	// action and command or path that the policy defers with Ask
+	Remember bool
+}
+
+// AskFunc answers requests the policy defers with Ask.
+type AskFunc func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error)
+
+// Outcome is reported to a DecisionHandler for every request the engine
+// answered.
+type Outcome struct {
+	Request components.SSEPermissionEventData
+	Result  Result
+	// Whether the decision came from an earlier remembered one
+	Remembered bool
+	// Whether the decision came from the asker
+	Asked bool
+}
+
+// DecisionHandler observes decisions, for example for logging.
+type DecisionHandler func(outcome Outcome)
+
+type engineOptions struct {
+	ask        AskFunc
+	onDecision DecisionHandler
+}
+
+type Option func(*engineOptions)
+
+// WithAsker answers requests the policy defers with Ask. Without one, those
+// requests are left pending for someone else to answer.
+func WithAsker(ask AskFunc) Option {
+	return func(o *engineOptions) {
+		o.ask = ask
+	}
+}
+
+// WithDecisionHandler registers a handler called after each answered request.
+func WithDecisionHandler(handler DecisionHandler) Option {
+	return func(o *engineOptions) {
+		o.onDecision = handler
+	}
+}
+
+type rememberKey struct {
+	sessionID, tool, action string
+	// command of Bash requests, whitespace-normalized, or the cleaned path
+	target string
+}
+
+func newRememberKey(req components.SSEPermissionEventData) rememberKey {
+	key := rememberKey{sessionID: req.SessionID, tool: ToolName(req.ToolName), action: req.Action}
+	if command := requestCommand(req); command != nil {
+		key.target = strings.Join(strings.Fields(*command), " ")
+	} else if path := requestPath(req); path != nil {
+		key.target = *path
+	}
+	return key
+}
+
+// Engine applies a Policy to permission requests and answers them through
+// Permissions.GrantPermission and DenyPermission. It is safe for concurrent
+// use.
+type Engine struct {
+	policy      *Policy
+	permissions *mix.Permissions
+	opts        engineOptions
+
+	mu         sync.Mutex
+	remembered map[rememberKey]Decision
+	answered   map[string]struct{}
+}
+
+// NewEngine creates an engine answering requests on behalf of client.
+func NewEngine(p *Policy, client *mix.Mix, opts ...Option) (*Engine, error) {
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+
+	var o engineOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Engine{
+		policy:      p,
+		permissions: client.Permissions,
+		opts:        o,
+		remembered:  map[rememberKey]Decision{},
+		answered:    map[string]struct{}{},
+	}, nil
+}
+
+// Remember records a decision for later requests in req's session for the
+// same tool and action, and the same command or path, if req has one.
+// Allowing `ls` therefore does not allow `rm -rf /`. A remembered decision
+// only answers requests the policy defers with Ask; rules that allow or deny
+// a request still apply.
+func (e *Engine) Remember(req components.SSEPermissionEventData, decision Decision) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remembered[newRememberKey(req)] = decision
+}
+
+// Forget drops the decisions remembered for a session.
+func (e *Engine) Forget(sessionID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for k := range e.remembered {
+		if k.sessionID == sessionID {
+			delete(e.remembered, k)
+		}
+	}
+}
+
+// Decide resolves a request to Allow or Deny from the policy and, for Ask
+// outcomes, remembered decisions or the asker. It returns ErrUnanswered when
+// the request needs an asker and none is configured.
+func (e *Engine) Decide(ctx context.Context, req components.SSEPermissionEventData) (Outcome, error) {
+	return e.decide(ctx, req, e.opts.ask)
+}
+
+// decide is Decide with ask in place of the configured asker.
+func (e *Engine) decide(ctx context.Context, req components.SSEPermissionEventData, ask AskFunc) (Outcome, error) {
+	res, err := e.policy.Evaluate(req)
+	if err != nil {
+		return Outcome{}, err
+	}
+	outcome := Outcome{Request: req, Result: res}
+
+	if res.Decision == Ask {
+		e.mu.Lock()
+		decision, ok := e.remembered[newRememberKey(req)]
+		e.mu.Unlock()
+		if ok {
+			outcome.Result = Result{Decision: decision, Rule: res.Rule}
+			outcome.Remembered = true
+			return outcome, nil
+		}
+
+		if ask == nil {
+			return outcome, ErrUnanswered
+		}
+		answer, err := ask(ctx, req)
+		if err != nil {
+			return outcome, err
+		}
+		if answer.Decision != Allow && answer.Decision != Deny {
+			return outcome, fmt.Errorf("policy: asker returned %q, want allow or deny", answer.Decision)
+		}
+		outcome.Result.Decision = answer.Decision
+		outcome.Result.Remember = answer.Remember
+		outcome.Asked = true
+	}
+
+	if outcome.Result.Remember {
+		e.Remember(req, outcome.Result.Decision)
+	}
+	return outcome, nil
+}
+
+// Handle answers the request carried by a permission event. Other events,
+// requests already answered by the engine, and requests left for someone else
+// are ignored.
+func (e *Engine) Handle(ctx context.Context, event *components.SSEEventStream) error {
+	if event == nil || event.SSEPermissionEvent == nil {
+		return nil
+	}
+	req := event.SSEPermissionEvent.Data
+
+	e.mu.Lock()
+	_, done := e.answered[req.ID]
+	e.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	outcome, err := e.Decide(ctx, req)
+	if errors.Is(err, ErrUnanswered) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if outcome.Result.Decision == Allow {
+		_, err = e.permissions.GrantPermission(ctx, req.ID)
+	} else {
+		_, err = e.permissions.DenyPermission(ctx, req.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("answering permission %s: %w", req.ID, err)
+	}
+
+	e.mu.Lock()
+	e.answered[req.ID] = struct{}{}
+	e.mu.Unlock()
+
+	if e.opts.onDecision != nil {
+		e.opts.onDecision(outcome)
+	}
+	return nil
+}
+
+// Run answers permission requests from src until the stream ends, ctx is
+// done, or answering a request fails.
+func (e *Engine) Run(ctx context.Context, src events.Source) error {
+	return events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		return e.Handle(ctx, event)
+	})
+}
+
+// PermissionHandler adapts the engine for Messages.SendMessageAndWait. Requests
+// that need an asker when none is configured fail the wait with ErrUnanswered.
+func (e *Engine) PermissionHandler() mix.PermissionHandler {
+	return func(ctx context.Context, req components.SSEPermissionEventData) (bool, error) {
+		outcome, err := e.Decide(ctx, req)
+		if err != nil {
+			return false, err
+		}
+		if e.opts.onDecision != nil {
+			e.opts.onDecision(outcome)
+		}
+		return outcome.Result.Decision == Allow, nil
+	}
+}
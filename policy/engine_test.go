@@ -0,0 +1,112 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mix "github.com/recreate-run/mix-go-sdk"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+func TestEngine_RememberedAnswers(t *testing.T) {
+	t.Parallel()
+	p := &Policy{Rules: []Rule{
+		{Name: "no env files", Tool: "Write", Path: "**/.env", Decision: Deny},
+	}}
+
+	var asked []string
+	engine, err := NewEngine(p, mix.New("http://localhost"), WithAsker(func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+		asked = append(asked, req.ID)
+		return Answer{Decision: Allow, Remember: true}, nil
+	}))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// The default asks; the answer is remembered for Write/write.
+	req := request("Write", "write", ptr("/tmp/notes"))
+	req.ID = "first"
+	outcome, err := engine.Decide(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, Allow, outcome.Result.Decision)
+	assert.True(t, outcome.Asked)
+
+	req.ID = "second"
+	outcome, err = engine.Decide(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, Allow, outcome.Result.Decision)
+	assert.True(t, outcome.Remembered)
+	assert.False(t, outcome.Asked)
+
+	// Rules still apply to the same tool and action.
+	outcome, err = engine.Decide(ctx, request("Write", "write", ptr("/workspace/app/.env")))
+	require.NoError(t, err)
+	assert.Equal(t, Deny, outcome.Result.Decision)
+	assert.False(t, outcome.Remembered)
+	assert.Equal(t, "no env files", outcome.Result.Rule.Name)
+
+	// Other sessions are asked again.
+	req.ID = "other"
+	req.SessionID = "s2"
+	outcome, err = engine.Decide(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, outcome.Asked)
+	assert.Equal(t, []string{"first", "other"}, asked)
+
+	engine.Forget("s1")
+	req.ID = "forgotten"
+	req.SessionID = "s1"
+	outcome, err = engine.Decide(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, outcome.Asked)
+}
+
+func TestEngine_Unanswered(t *testing.T) {
+	t.Parallel()
+	p, err := ParseYAML([]byte(yamlPolicy))
+	require.NoError(t, err)
+	engine, err := NewEngine(p, mix.New("http://localhost"))
+	require.NoError(t, err)
+
+	_, err = engine.Decide(context.Background(), request("github_create_issue", "call", nil))
+	assert.ErrorIs(t, err, ErrUnanswered)
+
+	engine.Remember(request("github_create_issue", "call", nil), Deny)
+	outcome, err := engine.Decide(context.Background(), request("github_create_issue", "call", nil))
+	require.NoError(t, err)
+	assert.Equal(t, Deny, outcome.Result.Decision)
+	assert.True(t, outcome.Remembered)
+}
+
+func TestEngine_RememberedCommand(t *testing.T) {
+	t.Parallel()
+	var asked []string
+	engine, err := NewEngine(&Policy{}, mix.New("http://localhost"), WithAsker(func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+		asked = append(asked, req.ID)
+		if req.ID == "ls" {
+			return Answer{Decision: Allow, Remember: true}, nil
+		}
+		return Answer{Decision: Deny}, nil
+	}))
+	require.NoError(t, err)
+
+	decide := func(id, command string) Outcome {
+		req := bash(command)
+		req.ID = id
+		outcome, err := engine.Decide(context.Background(), req)
+		require.NoError(t, err)
+		return outcome
+	}
+
+	assert.Equal(t, Allow, decide("ls", "ls").Result.Decision)
+	again := decide("ls again", "  ls ")
+	assert.Equal(t, Allow, again.Result.Decision)
+	assert.True(t, again.Remembered)
+
+	rm := decide("rm", "rm -rf /")
+	assert.Equal(t, Deny, rm.Result.Decision)
+	assert.False(t, rm.Remembered)
+	assert.Equal(t, []string{"ls", "rm"}, asked)
+}
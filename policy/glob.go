@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// glob is a compiled shell-style pattern. `*` matches any run of characters
+// other than the separator, `**` also matches separators, `?` matches a
+// single character and `[...]` a character class. Without a separator `*` and
+// `**` are equivalent. Wildcards also match line breaks, so that a pattern
+// cannot be sidestepped by a multi-line command.
+type glob struct {
+	pattern string
+	re      *regexp.Regexp
+	// number of literal characters, used to rank how specific a match is
+	literal int
+}
+
+func compileGlob(pattern string, sep byte) (*glob, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+
+	literal := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// A `**/` segment also matches no directory at all.
+				if sep != 0 && i+1 < len(pattern) && pattern[i+1] == sep {
+					i++
+					b.WriteString("(?:.*" + regexp.QuoteMeta(string(sep)) + ")?")
+					continue
+				}
+				b.WriteString(".*")
+				continue
+			}
+			if sep == 0 {
+				b.WriteString(".*")
+			} else {
+				b.WriteString("[^" + regexp.QuoteMeta(string(sep)) + "]*")
+			}
+		case '?':
+			if sep == 0 {
+				b.WriteString(".")
+			} else {
+				b.WriteString("[^" + regexp.QuoteMeta(string(sep)) + "]")
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("pattern %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				c = pattern[i]
+			}
+			fallthrough
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			literal++
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+	}
+	return &glob{pattern: pattern, re: re, literal: literal}, nil
+}
+
+func (g *glob) match(s string) bool {
+	return g.re.MatchString(s)
+}
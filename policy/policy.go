@@ -0,0 +1,302 @@
+// Package policy answers permission requests raised on a session's event
+// stream from declarative rules, so that unattended agents do not stall
+// waiting for Permissions.GrantPermission or DenyPermission.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// Decision is the outcome of a rule.
+type Decision string
+
+const (
+	// Allow grants the request.
+	Allow Decision = "allow"
+	// Deny denies the request.
+	Deny Decision = "deny"
+	// Ask defers the request to an asker, typically a human.
+	Ask Decision = "ask"
+)
+
+// Mode selects which rule applies when several match a request.
+type Mode string
+
+const (
+	// FirstMatch applies the first matching rule in policy order.
+	FirstMatch Mode = "first_match"
+	// MostSpecific applies the matching rule constraining the most fields,
+	// ranked by the number of literal characters in its patterns when tied,
+	// and by policy order after that.
+	MostSpecific Mode = "most_specific"
+)
+
+// Rule matches permission requests by glob patterns. Empty patterns match
+// anything. Tool matches the tool name; MCP tools are named
+// {server}_{tool}, so "github_*" matches every tool of the github server.
+// Path matches the request path, with `*` stopping at slashes and `**`
+// crossing them; . and .. elements of the request path are resolved first,
+// and a rule with a path never matches a request without one.
+// Command matches the command of Bash requests, with `*` matching any text; a
+// rule with a command never matches other requests. Since `*` would also match
+// chained commands, such as "go test ./... && rm -rf ~", an allow rule's
+// command never matches a command containing shell metacharacters: ; & | $ `
+// ( ) < > or a line break. Deny and ask rules still match them.
+type Rule struct {
+	Name     string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Tool     string   `json:"tool,omitempty" yaml:"tool,omitempty"`
+	Action   string   `json:"action,omitempty" yaml:"action,omitempty"`
+	Path     string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Command  string   `json:"command,omitempty" yaml:"command,omitempty"`
+	Decision Decision `json:"decision" yaml:"decision"`
+	// Remember applies the decision to later requests in the session for the
+	// same tool, action and command or path that the rules defer with Ask
+	Remember bool `json:"remember,omitempty" yaml:"remember,omitempty"`
+
+	tool, action, path, command *glob
+}
+
+// Policy is an ordered set of rules.
+type Policy struct {
+	// Defaults to FirstMatch
+	Mode Mode `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Decision when no rule matches; defaults to Ask
+	Default Decision `json:"default,omitempty" yaml:"default,omitempty"`
+	Rules   []Rule   `json:"rules" yaml:"rules"`
+
+	compiled bool
+}
+
+// Result is the outcome of evaluating a request.
+type Result struct {
+	Decision Decision
+	// The rule that matched, or nil when the default applied
+	Rule *Rule
+	// Whether the decision should be remembered for the session
+	Remember bool
+}
+
+// Load reads a policy from a JSON or YAML file, chosen by its extension.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p *Policy
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		p, err = ParseJSON(data)
+	case ".yaml", ".yml":
+		p, err = ParseYAML(data)
+	default:
+		return nil, fmt.Errorf("policy %s: unsupported file extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseJSON parses and validates a JSON policy. Unknown fields are rejected.
+func ParseJSON(data []byte) (*Policy, error) {
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, p.Compile()
+}
+
+// ParseYAML parses and validates a YAML policy. Unknown fields are rejected.
+func ParseYAML(data []byte) (*Policy, error) {
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, p.Compile()
+}
+
+// Compile validates the policy and prepares its patterns. It is called by the
+// parsers and by Evaluate for policies built in code.
+func (p *Policy) Compile() error {
+	switch p.Mode {
+	case "":
+		p.Mode = FirstMatch
+	case FirstMatch, MostSpecific:
+	default:
+		return fmt.Errorf("unknown mode %q", p.Mode)
+	}
+
+	switch p.Default {
+	case "":
+		p.Default = Ask
+	case Allow, Deny, Ask:
+	default:
+		return fmt.Errorf("unknown default decision %q", p.Default)
+	}
+
+	var errs []error
+	for i := range p.Rules {
+		if err := p.Rules[i].compile(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", p.Rules[i].label(i), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	p.compiled = true
+	return nil
+}
+
+// Evaluate returns the decision of the policy for a request.
+func (p *Policy) Evaluate(req components.SSEPermissionEventData) (Result, error) {
+	if !p.compiled {
+		if err := p.Compile(); err != nil {
+			return Result{}, err
+		}
+	}
+
+	tool := ToolName(req.ToolName)
+	command := requestCommand(req)
+	reqPath := requestPath(req)
+	var best *Rule
+	bestScore := -1
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !r.matches(tool, req.Action, reqPath, command) {
+			continue
+		}
+		if p.Mode == FirstMatch {
+			best = r
+			break
+		}
+		if score := r.specificity(); score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+
+	if best == nil {
+		return Result{Decision: p.Default}, nil
+	}
+	return Result{Decision: best.Decision, Rule: best, Remember: best.Remember}, nil
+}
+
+func (r *Rule) compile() error {
+	switch r.Decision {
+	case Allow, Deny, Ask:
+	case "":
+		return errors.New("missing decision")
+	default:
+		return fmt.Errorf("unknown decision %q", r.Decision)
+	}
+
+	var err error
+	if r.tool, err = compileOptional(r.Tool, 0); err != nil {
+		return err
+	}
+	if r.action, err = compileOptional(r.Action, 0); err != nil {
+		return err
+	}
+	if r.path, err = compileOptional(r.Path, '/'); err != nil {
+		return err
+	}
+	if r.command, err = compileOptional(r.Command, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+func compileOptional(pattern string, sep byte) (*glob, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return compileGlob(pattern, sep)
+}
+
+func (r *Rule) matches(tool, action string, path, command *string) bool {
+	if r.tool != nil && !r.tool.match(tool) {
+		return false
+	}
+	if r.action != nil && !r.action.match(action) {
+		return false
+	}
+	if r.path != nil && (path == nil || !r.path.match(*path)) {
+		return false
+	}
+	if r.command != nil && (command == nil || !r.command.match(*command)) {
+		return false
+	}
+	if r.command != nil && r.Decision == Allow && strings.ContainsAny(*command, shellMetacharacters) {
+		return false
+	}
+	return true
+}
+
+// specificity ranks a rule by the number of constrained fields, then by the
+// literal characters of its patterns.
+func (r *Rule) specificity() int {
+	score := 0
+	for _, g := range []*glob{r.tool, r.action, r.path, r.command} {
+		if g != nil && g.pattern != "*" && g.pattern != "**" {
+			score += 1 << 16
+			score += g.literal
+		}
+	}
+	return score
+}
+
+func (r *Rule) label(i int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("%q", r.Name)
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// requestPath returns the path of a request with . and .. elements resolved,
+// so that "/workspace/../etc/passwd" is matched as "/etc/passwd".
+func requestPath(req components.SSEPermissionEventData) *string {
+	if req.Path == nil {
+		return nil
+	}
+	cleaned := path.Clean(*req.Path)
+	return &cleaned
+}
+
+// shellMetacharacters chain, substitute or redirect commands. A command
+// containing any of them can do more than an allow rule's pattern suggests.
+const shellMetacharacters = ";&|`$()<>\n\r"
+
+// requestCommand returns the command of a Bash request, or nil.
+func requestCommand(req components.SSEPermissionEventData) *string {
+	if ToolName(req.ToolName) != string(components.CoreToolNameBash) {
+		return nil
+	}
+	params, err := req.Params.Bash()
+	if err != nil {
+		return nil
+	}
+	return &params.Command
+}
+
+// ToolName returns the name of a tool as matched by rules, which is
+// events.ToolName.
+func ToolName(name components.ToolName) string {
+	return events.ToolName(name)
+}
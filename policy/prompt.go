@@ -0,0 +1,307 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	mix "github.com/recreate-run/mix-go-sdk"
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// PermissionPrompter asks someone to decide a permission request. Prompt must
+// return promptly with ctx's error once ctx is done: the request timed out,
+// its turn ended, or the prompter is shutting down.
+type PermissionPrompter interface {
+	Prompt(ctx context.Context, req components.SSEPermissionEventData) (Answer, error)
+}
+
+// PromptFunc adapts a function to the PermissionPrompter interface.
+type PromptFunc func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error)
+
+func (f PromptFunc) Prompt(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+	return f(ctx, req)
+}
+
+// PromptOutcome is reported to a PromptHandler for every request the
+// PromptManager finished with.
+type PromptOutcome struct {
+	Request components.SSEPermissionEventData
+	Answer  Answer
+	// Whether the default decision was sent because no answer came in time or
+	// the prompter failed
+	Defaulted bool
+	// Whether the turn ended before an answer, in which case nothing is sent
+	Cancelled bool
+	// Whether the decision reached the server
+	Sent bool
+	// Failure of the prompter or of sending the decision, if any
+	Err error
+	// How the engine set with WithPromptEngine decided the request, or nil
+	// without one. Asked is false when a rule or a remembered answer decided
+	// it without prompting.
+	Policy *Outcome
+}
+
+// PromptHandler observes prompt outcomes, for example for logging.
+type PromptHandler func(outcome PromptOutcome)
+
+// DefaultPromptTimeout is how long a PromptManager waits for an answer by
+// default.
+const DefaultPromptTimeout = 5 * time.Minute
+
+type promptOptions struct {
+	timeout  time.Duration
+	fallback Decision
+	onDone   PromptHandler
+	engine   *Engine
+}
+
+type PromptOption func(*promptOptions)
+
+// WithPromptTimeout sets how long to wait for an answer before sending the
+// default decision. Zero waits until the turn ends.
+func WithPromptTimeout(d time.Duration) PromptOption {
+	return func(o *promptOptions) {
+		o.timeout = d
+	}
+}
+
+// WithDefaultDecision sets the decision sent when a prompt times out or the
+// prompter fails. The default is Deny.
+func WithDefaultDecision(decision Decision) PromptOption {
+	return func(o *promptOptions) {
+		o.fallback = decision
+	}
+}
+
+// WithPromptHandler registers a handler called once per request.
+func WithPromptHandler(handler PromptHandler) PromptOption {
+	return func(o *promptOptions) {
+		o.onDone = handler
+	}
+}
+
+// WithPromptEngine decides requests with engine first, prompting only for
+// those its policy defers with Ask. Answers with Remember set are remembered by
+// the engine, so later requests it would ask about are decided without
+// prompting. The engine's own asker is not used.
+func WithPromptEngine(engine *Engine) PromptOption {
+	return func(o *promptOptions) {
+		o.engine = engine
+	}
+}
+
+// PromptManager routes permission events to a PermissionPrompter and answers
+// them through Permissions.GrantPermission and DenyPermission. Each request
+// is prompted once, however many times its event is delivered. Prompts run
+// concurrently with the stream and are cancelled when the root turn
+// completes. It is safe for concurrent use.
+type PromptManager struct {
+	prompter    PermissionPrompter
+	permissions *mix.Permissions
+	opts        promptOptions
+
+	mu      sync.Mutex
+	pending map[string]context.CancelFunc
+	seen    map[string]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewPromptManager creates a manager prompting with prompter on behalf of
+// client.
+func NewPromptManager(prompter PermissionPrompter, client *mix.Mix, opts ...PromptOption) (*PromptManager, error) {
+	o := promptOptions{timeout: DefaultPromptTimeout, fallback: Deny}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.fallback != Allow && o.fallback != Deny {
+		return nil, fmt.Errorf("policy: default decision must be allow or deny, got %q", o.fallback)
+	}
+
+	return &PromptManager{
+		prompter:    prompter,
+		permissions: client.Permissions,
+		opts:        o,
+		pending:     map[string]context.CancelFunc{},
+		seen:        map[string]struct{}{},
+	}, nil
+}
+
+// Handle starts a prompt for a permission event and cancels pending prompts
+// when the root turn completes. Other events are ignored. ctx bounds the
+// prompt and sending its decision.
+func (m *PromptManager) Handle(ctx context.Context, event *components.SSEEventStream) {
+	switch {
+	case event == nil:
+	case event.SSEPermissionEvent != nil:
+		m.start(ctx, event.SSEPermissionEvent.Data)
+	case events.IsTurnComplete(event):
+		m.CancelPending()
+	}
+}
+
+// Pending returns the number of requests waiting for an answer.
+func (m *PromptManager) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pending)
+}
+
+// CancelPending cancels every pending prompt without answering it, for
+// example after cancelling processing.
+func (m *PromptManager) CancelPending() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cancel := range m.pending {
+		cancel()
+	}
+}
+
+// Wait blocks until every started prompt has finished.
+func (m *PromptManager) Wait() {
+	m.wg.Wait()
+}
+
+// Run prompts for permission events from src until the stream ends or ctx is
+// done, then cancels pending prompts and waits for them.
+func (m *PromptManager) Run(ctx context.Context, src events.Source) error {
+	defer m.Wait()
+	defer m.CancelPending()
+
+	return events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		m.Handle(ctx, event)
+		return nil
+	})
+}
+
+// Prompt asks for a decision synchronously, applying the timeout and default
+// decision but not deduplication. It fails only when ctx is done. It can be
+// passed to WithAsker so that an Engine prompts for Ask outcomes.
+func (m *PromptManager) Prompt(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+	answer, defaulted, err := m.ask(ctx, req)
+	if err != nil && !defaulted {
+		return Answer{}, err
+	}
+	return answer, nil
+}
+
+// PermissionHandler adapts the manager for Messages.SendMessageAndWait. The
+// call blocks until the request is answered, times out, or ctx is done. Like
+// Handle, it goes through the engine set with WithPromptEngine.
+func (m *PromptManager) PermissionHandler() mix.PermissionHandler {
+	return func(ctx context.Context, req components.SSEPermissionEventData) (bool, error) {
+		answer, defaulted, _, err := m.decide(ctx, req)
+		if err != nil && !defaulted {
+			return false, err
+		}
+		return answer.Decision == Allow, nil
+	}
+}
+
+func (m *PromptManager) start(ctx context.Context, req components.SSEPermissionEventData) {
+	m.mu.Lock()
+	if _, ok := m.seen[req.ID]; ok {
+		m.mu.Unlock()
+		return
+	}
+	m.seen[req.ID] = struct{}{}
+	promptCtx, cancel := context.WithCancel(ctx)
+	m.pending[req.ID] = cancel
+	m.wg.Add(1)
+	m.mu.Unlock()
+
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.mu.Lock()
+			delete(m.pending, req.ID)
+			m.mu.Unlock()
+			cancel()
+		}()
+
+		outcome := PromptOutcome{Request: req}
+		outcome.Answer, outcome.Defaulted, outcome.Policy, outcome.Err = m.decide(promptCtx, req)
+		if outcome.Err != nil && !outcome.Defaulted {
+			// The turn ended or the manager stopped: the request is moot.
+			outcome.Cancelled = true
+		} else if err := m.send(ctx, req.ID, outcome.Answer.Decision); err != nil {
+			outcome.Err = errors.Join(outcome.Err, err)
+		} else {
+			outcome.Sent = true
+		}
+
+		if m.opts.onDone != nil {
+			m.opts.onDone(outcome)
+		}
+	}()
+}
+
+// decide is ask, through the engine when the manager has one.
+func (m *PromptManager) decide(ctx context.Context, req components.SSEPermissionEventData) (Answer, bool, *Outcome, error) {
+	if m.opts.engine == nil {
+		answer, defaulted, err := m.ask(ctx, req)
+		return answer, defaulted, nil, err
+	}
+
+	var defaulted bool
+	var promptErr error
+	outcome, err := m.opts.engine.decide(ctx, req, func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+		var answer Answer
+		answer, defaulted, promptErr = m.ask(ctx, req)
+		if promptErr != nil && !defaulted {
+			return Answer{}, promptErr
+		}
+		return answer, nil
+	})
+	switch {
+	case ctx.Err() != nil:
+		return Answer{}, false, nil, ctx.Err()
+	case err != nil:
+		return Answer{Decision: m.opts.fallback}, true, nil, err
+	}
+	answer := Answer{Decision: outcome.Result.Decision, Remember: outcome.Result.Remember}
+	return answer, defaulted, &outcome, promptErr
+}
+
+// ask prompts for a decision. When the timeout passes it returns the default
+// decision with defaulted set; when the prompter fails, also its error. It
+// only fails without a decision when ctx is done.
+func (m *PromptManager) ask(ctx context.Context, req components.SSEPermissionEventData) (Answer, bool, error) {
+	askCtx := ctx
+	if m.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		askCtx, cancel = context.WithTimeout(ctx, m.opts.timeout)
+		defer cancel()
+	}
+
+	answer, err := m.prompter.Prompt(askCtx, req)
+	switch {
+	case ctx.Err() != nil:
+		return Answer{}, false, ctx.Err()
+	case askCtx.Err() != nil:
+		return Answer{Decision: m.opts.fallback}, true, nil
+	case err == nil && answer.Decision != Allow && answer.Decision != Deny:
+		err = fmt.Errorf("policy: prompter returned %q, want allow or deny", answer.Decision)
+	}
+	if err != nil {
+		return Answer{Decision: m.opts.fallback}, true, err
+	}
+	return answer, false, nil
+}
+
+func (m *PromptManager) send(ctx context.Context, id string, decision Decision) error {
+	var err error
+	if decision == Allow {
+		_, err = m.permissions.GrantPermission(ctx, id)
+	} else {
+		_, err = m.permissions.DenyPermission(ctx, id)
+	}
+	if err != nil {
+		return fmt.Errorf("answering permission %s: %w", id, err)
+	}
+	return nil
+}
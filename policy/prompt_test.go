@@ -0,0 +1,207 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mix "github.com/recreate-run/mix-go-sdk"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// permissionServer records the permission answers it receives.
+type permissionServer struct {
+	mu      sync.Mutex
+	answers []string
+}
+
+func (s *permissionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.answers = append(s.answers, strings.TrimPrefix(r.URL.Path, "/api/permissions/"))
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func (s *permissionServer) got() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.answers...)
+}
+
+func permissionEvent(id string) *components.SSEEventStream {
+	req := request("Bash", "execute", nil)
+	req.ID = id
+	event := components.CreateSSEEventStreamPermission(components.SSEPermissionEvent{Data: req})
+	return &event
+}
+
+func completeEvent() *components.SSEEventStream {
+	event := components.CreateSSEEventStreamComplete(components.SSECompleteEvent{Data: components.SSECompleteEventData{Done: true}})
+	return &event
+}
+
+func TestPromptManager(t *testing.T) {
+	t.Parallel()
+	srv := &permissionServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	prompter := PromptFunc(func(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+		if req.ID == "fast" {
+			return Answer{Decision: Allow}, nil
+		}
+		<-ctx.Done()
+		return Answer{}, ctx.Err()
+	})
+
+	var outcomes []PromptOutcome
+	var mu sync.Mutex
+	m, err := NewPromptManager(prompter, mix.New(ts.URL),
+		WithPromptTimeout(50*time.Millisecond),
+		WithPromptHandler(func(o PromptOutcome) {
+			mu.Lock()
+			outcomes = append(outcomes, o)
+			mu.Unlock()
+		}))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	m.Handle(ctx, permissionEvent("fast"))
+	m.Handle(ctx, permissionEvent("fast"))
+	m.Handle(ctx, permissionEvent("slow"))
+	m.Wait()
+	assert.ElementsMatch(t, []string{"fast/grant", "slow/deny"}, srv.got())
+
+	m, err = NewPromptManager(prompter, mix.New(ts.URL), WithPromptTimeout(0))
+	require.NoError(t, err)
+	m.Handle(ctx, permissionEvent("abandoned"))
+	assert.Equal(t, 1, m.Pending())
+	m.Handle(ctx, completeEvent())
+	m.Wait()
+	assert.Equal(t, 0, m.Pending())
+	assert.Len(t, srv.got(), 2, "a cancelled prompt is not answered")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, outcomes, 2)
+	for _, o := range outcomes {
+		assert.Equal(t, o.Request.ID == "slow", o.Defaulted)
+	}
+}
+
+func TestTerminalPrompter(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	p := NewTerminalPrompter(strings.NewReader("maybe\nalways\n"), &out)
+
+	req := request("Write", "write", ptr("/workspace/main.go"))
+	req.Description = "write main.go"
+	answer, err := p.Prompt(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, Answer{Decision: Allow, Remember: true}, answer)
+	assert.Contains(t, out.String(), "Write wants to write main.go\n  action:  write\n  path:    /workspace/main.go\n")
+	assert.Equal(t, 2, strings.Count(out.String(), "Allow?"))
+
+	_, err = p.Prompt(context.Background(), req)
+	assert.ErrorContains(t, err, "EOF")
+}
+
+func TestTerminalPrompter_StaleInput(t *testing.T) {
+	t.Parallel()
+	in, typed := io.Pipe()
+	defer typed.Close()
+	p := NewTerminalPrompter(in, io.Discard)
+	req := bash("rm -rf /")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.Prompt(ctx, req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A late answer to the withdrawn prompt must not answer the next one.
+	_, err = io.WriteString(typed, "y\n")
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	go io.WriteString(typed, "n\n")
+	answer, err := p.Prompt(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, Answer{Decision: Deny}, answer)
+}
+
+func TestTerminalPrompter_ControlCharacters(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	p := NewTerminalPrompter(strings.NewReader("n\n"), &out)
+
+	req := bash("rm -rf / \x1b[2K\r\n# ls")
+	req.Description = "list files\x1b[1A\u202e"
+	_, err := p.Prompt(context.Background(), req)
+	require.NoError(t, err)
+	assert.NotContains(t, out.String(), "\x1b")
+	assert.NotContains(t, out.String(), "\u202e")
+	assert.Contains(t, out.String(), "Bash wants to list files\\u001b[1A\\u202e\n")
+	assert.Contains(t, out.String(), "  command: rm -rf / \\u001b[2K\\u000d\\n# ls\n")
+}
+
+func TestPromptManager_Engine(t *testing.T) {
+	t.Parallel()
+	srv := &permissionServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := mix.New(ts.URL)
+
+	p := &Policy{Rules: []Rule{{Name: "no rm", Tool: "Bash", Command: "rm *", Decision: Deny}}}
+	engine, err := NewEngine(p, client)
+	require.NoError(t, err)
+
+	var outcomes []PromptOutcome
+	m, err := NewPromptManager(NewTerminalPrompter(strings.NewReader("always\n"), io.Discard), client,
+		WithPromptEngine(engine),
+		WithPromptHandler(func(o PromptOutcome) {
+			outcomes = append(outcomes, o)
+		}))
+	require.NoError(t, err)
+
+	// Handled one at a time, so that later requests see the remembered answer.
+	ctx := context.Background()
+	for i, command := range []string{"ls  -l", "ls -l", "rm -rf /"} {
+		req := bash(command)
+		req.ID = fmt.Sprint(i + 1)
+		event := components.CreateSSEEventStreamPermission(components.SSEPermissionEvent{Data: req})
+		m.Handle(ctx, &event)
+		m.Wait()
+	}
+
+	assert.Equal(t, []string{"1/grant", "2/grant", "3/deny"}, srv.got())
+	require.Len(t, outcomes, 3)
+	for _, o := range outcomes {
+		assert.True(t, o.Sent)
+	}
+	assert.True(t, outcomes[0].Policy.Asked)
+	assert.Equal(t, Answer{Decision: Allow, Remember: true}, outcomes[0].Answer)
+	assert.True(t, outcomes[1].Policy.Remembered)
+	assert.False(t, outcomes[1].Policy.Asked)
+	assert.Equal(t, "no rm", outcomes[2].Policy.Result.Rule.Name)
+
+	allowed, err := m.PermissionHandler()(ctx, bash("ls -l"))
+	require.NoError(t, err)
+	assert.True(t, allowed, "remembered without prompting")
+
+	// Another command is prompted; the prompter has no more input, so the
+	// default decision applies.
+	allowed, err = m.PermissionHandler()(ctx, bash("make"))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
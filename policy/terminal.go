@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// TerminalPrompter prompts on a terminal, one request at a time. Answers are
+// y(es), n(o), a(lways) to allow the same command or path for the rest of the
+// session, or (ne)v(er) to deny it for the rest of the session. Always and
+// never set Answer.Remember, which an Engine applies to later requests it
+// would ask about.
+type TerminalPrompter struct {
+	in  io.Reader
+	out io.Writer
+
+	// turn is held by the prompt currently owning the terminal
+	turn      chan struct{}
+	startOnce sync.Once
+	lines     chan line
+	readErr   error
+	// prompt numbers the prompts, so that input typed for one that was
+	// withdrawn is not taken as the answer to the next
+	prompt atomic.Uint64
+}
+
+// line is a line of input and the prompt that was showing when it was read.
+type line struct {
+	text   string
+	prompt uint64
+}
+
+var _ PermissionPrompter = (*TerminalPrompter)(nil)
+
+// NewTerminalPrompter creates a prompter reading answers from in, typically
+// os.Stdin, and writing prompts to out. in is read from a background
+// goroutine from the first prompt on.
+func NewTerminalPrompter(in io.Reader, out io.Writer) *TerminalPrompter {
+	return &TerminalPrompter{
+		in:    in,
+		out:   out,
+		turn:  make(chan struct{}, 1),
+		lines: make(chan line),
+	}
+}
+
+// Prompt shows a request and waits for an answer. When ctx is done first, the
+// prompt is withdrawn and ctx's error returned. Input read while no prompt
+// was showing, such as a late answer to a withdrawn prompt, is discarded.
+// Text from the request is shown with control characters escaped, so that it
+// cannot rewrite the terminal.
+func (p *TerminalPrompter) Prompt(ctx context.Context, req components.SSEPermissionEventData) (Answer, error) {
+	select {
+	case p.turn <- struct{}{}:
+		defer func() { <-p.turn }()
+	case <-ctx.Done():
+		return Answer{}, ctx.Err()
+	}
+	fmt.Fprint(p.out, formatRequest(req))
+	fmt.Fprint(p.out, "Allow? [y]es / [n]o / [a]lways / ne[v]er: ")
+	prompt := p.prompt.Add(1)
+	p.startOnce.Do(func() { go p.read() })
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(p.out, "\n(no longer waiting for an answer)")
+			return Answer{}, ctx.Err()
+		case l, ok := <-p.lines:
+			if !ok {
+				fmt.Fprintln(p.out)
+				return Answer{}, fmt.Errorf("policy: reading answer: %w", p.readErr)
+			}
+			if l.prompt != prompt {
+				// typed before this prompt was shown
+				continue
+			}
+			if answer, ok := parseAnswer(l.text); ok {
+				return answer, nil
+			}
+			fmt.Fprint(p.out, "Allow? [y]es / [n]o / [a]lways / ne[v]er: ")
+		}
+	}
+}
+
+func (p *TerminalPrompter) read() {
+	scanner := bufio.NewScanner(p.in)
+	for scanner.Scan() {
+		p.lines <- line{text: scanner.Text(), prompt: p.prompt.Load()}
+	}
+	p.readErr = scanner.Err()
+	if p.readErr == nil {
+		p.readErr = io.EOF
+	}
+	close(p.lines)
+}
+
+func parseAnswer(line string) (Answer, bool) {
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return Answer{Decision: Allow}, true
+	case "n", "no":
+		return Answer{Decision: Deny}, true
+	case "a", "always":
+		return Answer{Decision: Allow, Remember: true}, true
+	case "v", "never":
+		return Answer{Decision: Deny, Remember: true}, true
+	}
+	return Answer{}, false
+}
+
+// maxParamsLen bounds the params shown, since Write and Edit carry whole
+// file contents.
+const maxParamsLen = 500
+
+func formatRequest(req components.SSEPermissionEventData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s wants to %s\n", printable(ToolName(req.ToolName)), printable(req.Description))
+	fmt.Fprintf(&b, "  action:  %s\n", printable(req.Action))
+	if req.Path != nil {
+		fmt.Fprintf(&b, "  path:    %s\n", printable(*req.Path))
+	}
+	if command := requestCommand(req); command != nil {
+		fmt.Fprintf(&b, "  command: %s\n", printable(*command))
+	} else if params := req.Params.Raw(); len(params) > 0 && string(params) != "{}" {
+		fmt.Fprintf(&b, "  params:  %s\n", truncate(printable(string(params)), maxParamsLen))
+	}
+	return b.String()
+}
+
+// printable escapes control characters, including line breaks and escape
+// sequences, and bidirectional overrides, which could otherwise hide or
+// rewrite what the prompt shows.
+func printable(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case unicode.IsControl(r), r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,443 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+var (
+	// ErrQueueClosed is returned for messages still queued when a
+	// MessageQueue is closed, and for messages enqueued afterwards.
+	ErrQueueClosed = errors.New("message queue closed")
+	// ErrMessageStale is returned for a message that waited in the queue
+	// longer than its stale deadline and was dropped without being sent.
+	ErrMessageStale = errors.New("queued message went stale")
+	// ErrMessageDropped is returned for a message removed from the queue
+	// before it was sent.
+	ErrMessageDropped = errors.New("queued message dropped")
+)
+
+type queueOptions struct {
+	waitOpts   []WaitOption
+	staleAfter time.Duration
+	onDepth    func(depth int)
+}
+
+type QueueOption func(*queueOptions)
+
+// WithQueueWaitOptions applies wait options, such as permission handlers, to
+// every message sent by the queue.
+func WithQueueWaitOptions(opts ...WaitOption) QueueOption {
+	return func(o *queueOptions) {
+		o.waitOpts = append(o.waitOpts, opts...)
+	}
+}
+
+// WithQueueStaleAfter sets the default stale deadline of queued messages.
+func WithQueueStaleAfter(d time.Duration) QueueOption {
+	return func(o *queueOptions) {
+		o.staleAfter = d
+	}
+}
+
+// WithQueueDepthHandler is called with the number of queued messages, not
+// counting the one being sent, every time it changes. It may be called from
+// several goroutines at once.
+func WithQueueDepthHandler(handler func(depth int)) QueueOption {
+	return func(o *queueOptions) {
+		o.onDepth = handler
+	}
+}
+
+type enqueueOptions struct {
+	priority   int
+	staleAfter *time.Duration
+	waitOpts   []WaitOption
+}
+
+type EnqueueOption func(*enqueueOptions)
+
+// WithPriority sends the message ahead of queued messages with a lower
+// priority. Messages of equal priority are sent in the order they were
+// enqueued. The default priority is zero.
+func WithPriority(priority int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = priority
+	}
+}
+
+// WithStaleAfter drops the message with ErrMessageStale if it has not been
+// sent within d of being enqueued. Zero disables the deadline.
+func WithStaleAfter(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.staleAfter = &d
+	}
+}
+
+// WithEnqueueWaitOptions applies wait options to this message, after those
+// of the queue.
+func WithEnqueueWaitOptions(opts ...WaitOption) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.waitOpts = append(o.waitOpts, opts...)
+	}
+}
+
+// QueuedMessage is a message submitted to a MessageQueue.
+type QueuedMessage struct {
+	ctx        context.Context
+	body       operations.SendMessageRequestBody
+	priority   int
+	seq        uint64
+	enqueuedAt time.Time
+	staleAfter time.Duration
+	waitOpts   []WaitOption
+
+	done   chan struct{}
+	result *MessageResult
+	err    error
+}
+
+// Done is closed once the message's turn completed or the message failed or
+// was dropped.
+func (m *QueuedMessage) Done() <-chan struct{} {
+	return m.done
+}
+
+// Wait blocks until the message's turn completes and returns its result. If
+// ctx is done first, Wait returns the context error but the message stays
+// queued; use MessageQueue.Remove to withdraw it.
+func (m *QueuedMessage) Wait(ctx context.Context) (*MessageResult, error) {
+	select {
+	case <-m.done:
+		return m.result, m.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *QueuedMessage) finish(result *MessageResult, err error) {
+	m.result, m.err = result, err
+	close(m.done)
+}
+
+func (m *QueuedMessage) isStale(now time.Time) bool {
+	return m.staleAfter > 0 && now.Sub(m.enqueuedAt) > m.staleAfter
+}
+
+// MessageQueue serializes messages sent to one session so that concurrent
+// producers can share a conversation. Each message is sent with
+// SendMessageAndWait once the previous turn completed. When waiting for a
+// turn failed without cancelling it, processing is cancelled before the next
+// message is sent; if that fails too, the message fails rather than start a
+// second turn. It is safe for concurrent use.
+type MessageQueue struct {
+	messages  *Messages
+	sessionID string
+	opts      queueOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	queue    []*QueuedMessage
+	inFlight *QueuedMessage
+	seq      uint64
+	closed   bool
+	wake     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewQueue creates a queue for a session and starts dispatching. ctx bounds
+// the lifetime of the queue; Close stops it explicitly.
+func (s *Messages) NewQueue(ctx context.Context, id string, opts ...QueueOption) *MessageQueue {
+	var o queueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	queueCtx, cancel := context.WithCancel(ctx)
+	q := &MessageQueue{
+		messages:  s,
+		sessionID: id,
+		opts:      o,
+		ctx:       queueCtx,
+		cancel:    cancel,
+		wake:      make(chan struct{}, 1),
+		stopped:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue adds a message to the queue and returns immediately. ctx bounds the
+// message: if it is done before the message is sent, the message is dropped,
+// and if it is done while the turn runs, waiting for the turn stops as with
+// SendMessageAndWait.
+func (q *MessageQueue) Enqueue(ctx context.Context, body operations.SendMessageRequestBody, opts ...EnqueueOption) *QueuedMessage {
+	o := enqueueOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m := &QueuedMessage{
+		ctx:        ctx,
+		body:       body,
+		priority:   o.priority,
+		enqueuedAt: time.Now(),
+		staleAfter: q.opts.staleAfter,
+		waitOpts:   append(append([]WaitOption(nil), q.opts.waitOpts...), o.waitOpts...),
+		done:       make(chan struct{}),
+	}
+	if o.staleAfter != nil {
+		m.staleAfter = *o.staleAfter
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		m.finish(nil, ErrQueueClosed)
+		return m
+	}
+	q.seq++
+	m.seq = q.seq
+	q.queue = append(q.queue, m)
+	sort.SliceStable(q.queue, func(i, j int) bool {
+		if q.queue[i].priority != q.queue[j].priority {
+			return q.queue[i].priority > q.queue[j].priority
+		}
+		return q.queue[i].seq < q.queue[j].seq
+	})
+	depth := len(q.queue)
+	q.mu.Unlock()
+
+	q.notifyDepth(depth)
+	q.signal()
+	return m
+}
+
+// Send enqueues a message and waits for its turn to complete. If ctx is done
+// while the message is still queued, it is removed from the queue.
+func (q *MessageQueue) Send(ctx context.Context, body operations.SendMessageRequestBody, opts ...EnqueueOption) (*MessageResult, error) {
+	m := q.Enqueue(ctx, body, opts...)
+	result, err := m.Wait(ctx)
+	if err != nil && ctx.Err() != nil {
+		if q.Remove(m) {
+			return nil, ctx.Err()
+		}
+		// The message is already being sent; its turn ends on the same ctx.
+		<-m.done
+		return m.result, m.err
+	}
+	return result, err
+}
+
+// Remove withdraws a message that has not been sent yet. Its Wait returns
+// ErrMessageDropped. It reports whether the message was still queued.
+func (q *MessageQueue) Remove(m *QueuedMessage) bool {
+	q.mu.Lock()
+	removed := false
+	for i, queued := range q.queue {
+		if queued == m {
+			q.queue = append(q.queue[:i], q.queue[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	depth := len(q.queue)
+	q.mu.Unlock()
+
+	if removed {
+		m.finish(nil, ErrMessageDropped)
+		q.notifyDepth(depth)
+	}
+	return removed
+}
+
+// DropStale removes queued messages that have passed their stale deadline, or
+// that have waited longer than olderThan when it is positive, and returns how
+// many were dropped. Their Wait returns ErrMessageStale.
+func (q *MessageQueue) DropStale(olderThan time.Duration) int {
+	now := time.Now()
+
+	q.mu.Lock()
+	var dropped []*QueuedMessage
+	kept := q.queue[:0]
+	for _, m := range q.queue {
+		if m.isStale(now) || (olderThan > 0 && now.Sub(m.enqueuedAt) > olderThan) {
+			dropped = append(dropped, m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	q.queue = kept
+	depth := len(q.queue)
+	q.mu.Unlock()
+
+	for _, m := range dropped {
+		m.finish(nil, ErrMessageStale)
+	}
+	if len(dropped) > 0 {
+		q.notifyDepth(depth)
+	}
+	return len(dropped)
+}
+
+// Len returns the number of queued messages, not counting the one being sent.
+func (q *MessageQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// Busy reports whether a message is being sent or its turn is running.
+func (q *MessageQueue) Busy() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.inFlight != nil
+}
+
+// Close stops the queue. Queued messages fail with ErrQueueClosed and waiting
+// for the turn in flight stops. Close returns once the dispatcher exited.
+func (q *MessageQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		<-q.stopped
+		return nil
+	}
+	q.closed = true
+	pending := q.queue
+	q.queue = nil
+	q.mu.Unlock()
+
+	q.cancel()
+	for _, m := range pending {
+		m.finish(nil, ErrQueueClosed)
+	}
+	if len(pending) > 0 {
+		q.notifyDepth(0)
+	}
+	<-q.stopped
+	return nil
+}
+
+func (q *MessageQueue) run() {
+	defer close(q.stopped)
+
+	// unsettled is the error that ended the previous turn while it may still
+	// be running on the server.
+	var unsettled error
+	for {
+		m, ok := q.next()
+		if !ok {
+			return
+		}
+
+		// A new message must not start a turn next to the previous one.
+		if unsettled != nil {
+			if cancelErr := q.messages.cancelProcessing(q.ctx, q.sessionID, unsettled); cancelErr.Err != nil {
+				q.mu.Lock()
+				q.inFlight = nil
+				q.mu.Unlock()
+				m.finish(nil, fmt.Errorf("previous turn may still be running: %w", cancelErr))
+				continue
+			}
+			unsettled = nil
+		}
+
+		ctx, cancel := context.WithCancel(m.ctx)
+		stop := context.AfterFunc(q.ctx, cancel)
+		result, err := q.messages.SendMessageAndWait(ctx, q.sessionID, m.body, m.waitOpts...)
+		stop()
+		cancel()
+
+		if err != nil && !turnSettled(err) {
+			unsettled = err
+		}
+
+		q.mu.Lock()
+		q.inFlight = nil
+		q.mu.Unlock()
+		m.finish(result, err)
+	}
+}
+
+// turnSettled reports whether the error that ended a turn shows it is no
+// longer running, because processing was cancelled.
+func turnSettled(err error) bool {
+	var exceeded *BudgetExceededError
+	if errors.As(err, &exceeded) && exceeded.Cancel != nil {
+		err = exceeded.Cancel
+	}
+	var cancelErr *apierrors.CancelError
+	return errors.As(err, &cancelErr) && cancelErr.Acknowledged
+}
+
+// next blocks until a message is ready to be sent, dropping messages that went
+// stale or whose context is done on the way. It returns false once the queue
+// is closed.
+func (q *MessageQueue) next() (*QueuedMessage, bool) {
+	for {
+		q.mu.Lock()
+		if q.closed || q.ctx.Err() != nil {
+			q.closed = true
+			pending := q.queue
+			q.queue = nil
+			q.mu.Unlock()
+			for _, m := range pending {
+				m.finish(nil, ErrQueueClosed)
+			}
+			return nil, false
+		}
+		if len(q.queue) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-q.wake:
+			case <-q.ctx.Done():
+			}
+			continue
+		}
+
+		m := q.queue[0]
+		q.queue = q.queue[1:]
+		depth := len(q.queue)
+
+		var err error
+		switch {
+		case m.isStale(time.Now()):
+			err = ErrMessageStale
+		case m.ctx.Err() != nil:
+			err = m.ctx.Err()
+		default:
+			q.inFlight = m
+		}
+		q.mu.Unlock()
+
+		q.notifyDepth(depth)
+		if err != nil {
+			m.finish(nil, err)
+			continue
+		}
+		return m, true
+	}
+}
+
+func (q *MessageQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *MessageQueue) notifyDepth(depth int) {
+	if q.opts.onDepth != nil {
+		q.opts.onDepth(depth)
+	}
+}
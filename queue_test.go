@@ -0,0 +1,148 @@
+package mix
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+func message(text string) operations.SendMessageRequestBody {
+	return operations.SendMessageRequestBody{Text: text}
+}
+
+func sentMessages(srv *agentServer) []string {
+	var out []string
+	for _, req := range srv.received() {
+		if text, ok := strings.CutPrefix(req, "message:"); ok {
+			out = append(out, text)
+		}
+	}
+	return out
+}
+
+func TestMessageQueue_OneTurnAtATime(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		if text == "first" {
+			<-release
+		}
+		a.content("re: " + text)
+		a.complete("m-" + text)
+	})
+
+	var mu sync.Mutex
+	var depths []int
+	q := client.Messages.NewQueue(context.Background(), "s1",
+		WithQueueWaitOptions(WithoutUsage()),
+		WithQueueDepthHandler(func(depth int) {
+			mu.Lock()
+			depths = append(depths, depth)
+			mu.Unlock()
+		}))
+	defer q.Close()
+
+	ctx := context.Background()
+	first := q.Enqueue(ctx, message("first"))
+	srv.waitFor(t, "message:first")
+	assert.True(t, q.Busy())
+
+	low := q.Enqueue(ctx, message("low"))
+	high := q.Enqueue(ctx, message("high"), WithPriority(1))
+	assert.Equal(t, 2, q.Len())
+	assert.Equal(t, []string{"first"}, sentMessages(srv), "nothing is sent while a turn runs")
+	close(release)
+
+	for text, m := range map[string]*QueuedMessage{"first": first, "low": low, "high": high} {
+		res, err := m.Wait(ctx)
+		require.NoError(t, err, text)
+		assert.Equal(t, "re: "+text, res.Content)
+	}
+	assert.Equal(t, []string{"first", "high", "low"}, sentMessages(srv))
+	assert.False(t, q.Busy())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, depths)
+	assert.Equal(t, 2, slices.Max(depths))
+	assert.Equal(t, 0, depths[len(depths)-1])
+}
+
+func TestMessageQueue_CancelsUnsettledTurn(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		if text == "stuck" {
+			return
+		}
+		a.complete("m-" + text)
+	})
+
+	q := client.Messages.NewQueue(context.Background(), "s1", WithQueueWaitOptions(WithoutUsage()))
+	defer q.Close()
+
+	// The caller stops waiting, but the turn keeps running on the server.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := q.Send(ctx, message("stuck"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, srv.received(), "cancel")
+
+	res, err := q.Send(context.Background(), message("next"))
+	require.NoError(t, err)
+	assert.True(t, res.Done)
+	assert.Equal(t, []string{"message:stuck", "cancel", "message:next"}, srv.received())
+
+	// A completed turn needs no cancel.
+	_, err = q.Send(context.Background(), message("last"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message:stuck", "cancel", "message:next", "message:last"}, srv.received())
+}
+
+func TestMessageQueue_Drops(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	_, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		<-release
+		a.complete("m-" + text)
+	})
+
+	q := client.Messages.NewQueue(context.Background(), "s1", WithQueueWaitOptions(WithoutUsage()))
+	ctx := context.Background()
+	busy := q.Enqueue(ctx, message("busy"))
+	require.Eventually(t, q.Busy, 5*time.Second, 5*time.Millisecond)
+
+	removed := q.Enqueue(ctx, message("removed"))
+	stale := q.Enqueue(ctx, message("stale"), WithStaleAfter(time.Millisecond))
+	pending := q.Enqueue(ctx, message("pending"))
+
+	assert.True(t, q.Remove(removed))
+	assert.False(t, q.Remove(removed))
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, q.DropStale(0))
+
+	_, err := removed.Wait(ctx)
+	assert.ErrorIs(t, err, ErrMessageDropped)
+	_, err = stale.Wait(ctx)
+	assert.ErrorIs(t, err, ErrMessageStale)
+
+	close(release)
+	_, err = busy.Wait(ctx)
+	require.NoError(t, err)
+	_, err = pending.Wait(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, q.Close())
+	_, err = q.Enqueue(ctx, message("late")).Wait(ctx)
+	assert.ErrorIs(t, err, ErrQueueClosed)
+}
@@ -0,0 +1,364 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// SessionChangeKind describes how a session in a SessionRegistry changed.
+type SessionChangeKind string
+
+const (
+	SessionAdded   SessionChangeKind = "added"
+	SessionUpdated SessionChangeKind = "updated"
+	SessionRemoved SessionChangeKind = "removed"
+)
+
+// SessionChange is delivered to a SessionChangeHandler. For removals Session
+// holds the last known state.
+type SessionChange struct {
+	Kind    SessionChangeKind
+	Session components.SessionData
+}
+
+// SessionChangeHandler is called for every change to a SessionRegistry, in
+// order, from the goroutine that applied the change.
+type SessionChangeHandler func(change SessionChange)
+
+// SessionSubscriptionHandler receives the stream subscription opened for a
+// session tracked by a SessionRegistry. It is called from the goroutine that
+// opened the subscription. The registry closes the subscription when the
+// session is removed or the registry is closed.
+type SessionSubscriptionHandler func(session components.SessionData, sub *Subscription)
+
+// SessionErrorHandler is called when a SessionRegistry fails to fetch the
+// details of a session or to open its subscription.
+type SessionErrorHandler func(sessionID string, err error)
+
+type registryOptions struct {
+	includeSubagents bool
+	onChange         SessionChangeHandler
+	onSubscribe      SessionSubscriptionHandler
+	onError          SessionErrorHandler
+	subscribeOpts    []SubscribeOption
+	fetchDetails     bool
+}
+
+type RegistryOption func(*registryOptions)
+
+// WithSubagentSessions also tracks subagent sessions.
+func WithSubagentSessions() RegistryOption {
+	return func(o *registryOptions) {
+		o.includeSubagents = true
+	}
+}
+
+// WithSessionChangeHandler registers a handler for session changes.
+func WithSessionChangeHandler(handler SessionChangeHandler) RegistryOption {
+	return func(o *registryOptions) {
+		o.onChange = handler
+	}
+}
+
+// WithSessionSubscriptions opens a stream subscription for every tracked
+// session in the background and hands it to handler, which owns reading from
+// it. Sessions whose subscription cannot be opened are reported to the
+// SessionErrorHandler.
+func WithSessionSubscriptions(handler SessionSubscriptionHandler, opts ...SubscribeOption) RegistryOption {
+	return func(o *registryOptions) {
+		o.onSubscribe = handler
+		o.subscribeOpts = opts
+	}
+}
+
+// WithSessionDetails fetches the full session with GetSession when a
+// session_created event arrives, instead of tracking only the ID, title and
+// creation time carried by the event. Without WithSubagentSessions the
+// session is always fetched, to tell subagent sessions apart.
+func WithSessionDetails() RegistryOption {
+	return func(o *registryOptions) {
+		o.fetchDetails = true
+	}
+}
+
+// WithSessionErrorHandler registers a handler for failures to fetch a session
+// or open its subscription.
+func WithSessionErrorHandler(handler SessionErrorHandler) RegistryOption {
+	return func(o *registryOptions) {
+		o.onError = handler
+	}
+}
+
+// SessionRegistry keeps a live view of the sessions on the server. It is
+// bootstrapped from ListSessions and kept current by applying session_created
+// and session_deleted events. It is safe for concurrent use.
+type SessionRegistry struct {
+	sessions *Sessions
+	opts     registryOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	byID     map[string]components.SessionData
+	subs     map[string]*Subscription
+	closed   bool
+	notifyMu sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// NewRegistry lists the current sessions and returns a registry tracking
+// them. ctx bounds the lifetime of subscriptions opened by the registry.
+func (s *Sessions) NewRegistry(ctx context.Context, opts ...RegistryOption) (*SessionRegistry, error) {
+	var o registryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	regCtx, cancel := context.WithCancel(ctx)
+	r := &SessionRegistry{
+		sessions: s,
+		opts:     o,
+		ctx:      regCtx,
+		cancel:   cancel,
+		byID:     map[string]components.SessionData{},
+		subs:     map[string]*Subscription{},
+	}
+
+	if err := r.Refresh(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Refresh re-lists sessions from the server and applies the difference, for
+// example after the event stream was interrupted.
+func (r *SessionRegistry) Refresh(ctx context.Context) error {
+	res, err := r.sessions.ListSessions(ctx, &r.opts.includeSubagents)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{}, len(res.SessionData))
+	for _, session := range res.SessionData {
+		seen[session.ID] = struct{}{}
+		r.put(session)
+	}
+
+	for _, session := range r.Snapshot() {
+		if _, ok := seen[session.ID]; !ok {
+			r.remove(session.ID)
+		}
+	}
+
+	return nil
+}
+
+// Snapshot returns the tracked sessions ordered by creation time.
+func (r *SessionRegistry) Snapshot() []components.SessionData {
+	r.mu.Lock()
+	out := make([]components.SessionData, 0, len(r.byID))
+	for _, session := range r.byID {
+		out = append(out, session)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Get returns a tracked session.
+func (r *SessionRegistry) Get(id string) (components.SessionData, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.byID[id]
+	return session, ok
+}
+
+// Len returns the number of tracked sessions.
+func (r *SessionRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.byID)
+}
+
+// Apply updates the registry from a session_created or session_deleted event
+// and reports whether the event was one of them.
+func (r *SessionRegistry) Apply(event *components.SSEEventStream) bool {
+	switch {
+	case event == nil:
+		return false
+	case event.SSESessionCreatedEvent != nil:
+		data := event.SSESessionCreatedEvent.Data
+		session := components.SessionData{
+			ID:        data.SessionID,
+			Title:     data.Title,
+			CreatedAt: time.Unix(data.CreatedAt, 0),
+		}
+		known, ok := r.Get(data.SessionID)
+		if ok {
+			known.Title = data.Title
+			session = known
+		}
+		// The event does not say whether the session belongs to a subagent,
+		// so filtering them out needs the details of new sessions.
+		needDetails := !ok && !r.opts.includeSubagents
+		if r.opts.fetchDetails || needDetails {
+			res, err := r.sessions.GetSession(r.ctx, data.SessionID)
+			if err == nil && res.SessionData == nil {
+				err = errors.New("response did not contain a session")
+			}
+			switch {
+			case err == nil:
+				session = *res.SessionData
+			case needDetails:
+				// A later Refresh adds the session if it is not a subagent.
+				r.reportError(data.SessionID, err)
+				return true
+			default:
+				r.reportError(data.SessionID, err)
+			}
+		}
+		if !r.opts.includeSubagents && session.ParentSessionID != nil {
+			return true
+		}
+		r.put(session)
+		return true
+	case event.SSESessionDeletedEvent != nil:
+		r.remove(event.SSESessionDeletedEvent.Data.SessionID)
+		return true
+	}
+	return false
+}
+
+// Run applies events from src until the stream ends, ctx is done, or the
+// registry is closed.
+func (r *SessionRegistry) Run(ctx context.Context, src events.Source) error {
+	return events.Each(ctx, src, func(event *components.SSEEventStream) error {
+		r.Apply(event)
+		return nil
+	})
+}
+
+// Close closes every subscription opened by the registry, once the pending
+// ones are opened and handed over. The snapshot stays readable but is no
+// longer updated.
+func (r *SessionRegistry) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+
+	r.mu.Lock()
+	subs := r.subs
+	r.subs = map[string]*Subscription{}
+	r.mu.Unlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *SessionRegistry) put(session components.SessionData) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	prev, existed := r.byID[session.ID]
+	r.byID[session.ID] = session
+	subscribe := !existed && r.opts.onSubscribe != nil
+	if subscribe {
+		r.wg.Add(1)
+	}
+	r.mu.Unlock()
+
+	if subscribe {
+		go r.subscribe(session)
+	}
+	if existed && reflect.DeepEqual(prev, session) {
+		return
+	}
+
+	kind := SessionUpdated
+	if !existed {
+		kind = SessionAdded
+	}
+	r.notify(SessionChange{Kind: kind, Session: session})
+}
+
+func (r *SessionRegistry) remove(id string) {
+	r.mu.Lock()
+	session, ok := r.byID[id]
+	delete(r.byID, id)
+	sub := r.subs[id]
+	delete(r.subs, id)
+	r.mu.Unlock()
+
+	if sub != nil {
+		sub.Close()
+	}
+	if ok {
+		r.notify(SessionChange{Kind: SessionRemoved, Session: session})
+	}
+}
+
+func (r *SessionRegistry) subscribe(session components.SessionData) {
+	defer r.wg.Done()
+
+	sub, err := r.sessions.rootSDK.Streaming.Subscribe(r.ctx, session.ID, r.opts.subscribeOpts...)
+	if err != nil {
+		// The session may have been deleted in the meantime; a later Refresh
+		// or session_deleted event reconciles the registry.
+		if r.ctx.Err() == nil {
+			r.reportError(session.ID, err)
+		}
+		return
+	}
+
+	r.mu.Lock()
+	if _, ok := r.byID[session.ID]; !ok || r.closed {
+		r.mu.Unlock()
+		sub.Close()
+		return
+	}
+	r.subs[session.ID] = sub
+	r.mu.Unlock()
+
+	r.opts.onSubscribe(session, sub)
+}
+
+func (r *SessionRegistry) reportError(sessionID string, err error) {
+	if r.opts.onError != nil {
+		r.opts.onError(sessionID, err)
+	}
+}
+
+func (r *SessionRegistry) notify(change SessionChange) {
+	if r.opts.onChange == nil {
+		return
+	}
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+	r.opts.onChange(change)
+}
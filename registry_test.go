@@ -0,0 +1,160 @@
+package mix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/components"
+)
+
+// sessionServer serves ListSessions and GetSession from a fixed set of
+// sessions, and holds stream requests open without responding, as a server
+// slow to accept subscriptions would. Streams of unknown sessions fail.
+type sessionServer struct {
+	listed   []string
+	sessions map[string]string
+}
+
+func (s *sessionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	notFound := func() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":404,"message":"not found","type":"not_found"}}`))
+	}
+
+	switch {
+	case r.URL.Path == "/api/sessions":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[" + strings.Join(s.listed, ",") + "]"))
+	case strings.HasPrefix(r.URL.Path, "/api/sessions/"):
+		session, ok := s.sessions[strings.TrimPrefix(r.URL.Path, "/api/sessions/")]
+		if !ok {
+			notFound()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(session))
+	case r.URL.Path == "/stream":
+		if _, ok := s.sessions[r.URL.Query().Get("sessionId")]; !ok {
+			notFound()
+			return
+		}
+		<-r.Context().Done()
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func sessionJSON(t *testing.T, id string, parent *string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"id":              id,
+		"title":           "session " + id,
+		"createdAt":       "2026-01-01T00:00:00Z",
+		"parentSessionId": parent,
+	})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func sessionCreated(id string) *components.SSEEventStream {
+	event := components.CreateSSEEventStreamSessionCreated(components.SSESessionCreatedEvent{
+		Data: components.SSESessionCreatedEventData{SessionID: id, Title: "session " + id, CreatedAt: 1767225600},
+	})
+	return &event
+}
+
+type sessionErrors struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+func (e *sessionErrors) handle(sessionID string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.errs == nil {
+		e.errs = map[string]error{}
+	}
+	e.errs[sessionID] = err
+}
+
+func (e *sessionErrors) ids() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var ids []string
+	for id := range e.errs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestSessionRegistry_FiltersSubagentSessions(t *testing.T) {
+	t.Parallel()
+	root1 := sessionJSON(t, "root1", nil)
+	srv := &sessionServer{
+		listed: []string{root1},
+		sessions: map[string]string{
+			"root1": root1,
+			"root2": sessionJSON(t, "root2", nil),
+			"child": sessionJSON(t, "child", String("root1")),
+		},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var errs sessionErrors
+	reg, err := New(ts.URL).Sessions.NewRegistry(context.Background(), WithSessionErrorHandler(errs.handle))
+	require.NoError(t, err)
+	defer reg.Close()
+
+	assert.True(t, reg.Apply(sessionCreated("child")))
+	assert.True(t, reg.Apply(sessionCreated("root2")))
+	assert.True(t, reg.Apply(sessionCreated("gone")))
+
+	var ids []string
+	for _, session := range reg.Snapshot() {
+		ids = append(ids, session.ID)
+	}
+	assert.ElementsMatch(t, []string{"root1", "root2"}, ids)
+	assert.Equal(t, []string{"gone"}, errs.ids())
+}
+
+func TestSessionRegistry_SubscribesInBackground(t *testing.T) {
+	t.Parallel()
+	srv := &sessionServer{
+		listed:   []string{sessionJSON(t, "slow", nil), sessionJSON(t, "missing", nil)},
+		sessions: map[string]string{"slow": sessionJSON(t, "slow", nil)},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var errs sessionErrors
+	handed := make(chan string, 2)
+	start := time.Now()
+	reg, err := New(ts.URL).Sessions.NewRegistry(context.Background(),
+		WithSessionErrorHandler(errs.handle),
+		WithSessionSubscriptions(func(session components.SessionData, sub *Subscription) {
+			handed <- session.ID
+		}))
+	require.NoError(t, err)
+	defer reg.Close()
+	assert.Less(t, time.Since(start), time.Second, "NewRegistry does not wait for subscriptions")
+	assert.Equal(t, 2, reg.Len())
+
+	require.Eventually(t, func() bool { return len(errs.ids()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"missing"}, errs.ids())
+
+	// Closing abandons the pending subscription without reporting it.
+	require.NoError(t, reg.Close())
+	assert.Empty(t, handed)
+	assert.Equal(t, []string{"missing"}, errs.ids())
+}
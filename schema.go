@@ -0,0 +1,286 @@
+package mix
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jsonSchema is the subset of JSON Schema derived from Go types and checked by
+// validate.
+type jsonSchema struct {
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Nullable             bool                   `json:"-"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaFor derives a JSON Schema from a Go type following encoding/json
+// rules: exported fields named by their json tag, omitempty and pointer fields
+// optional. A `description` struct tag documents a field and an `enum` tag
+// lists its allowed values, separated by commas.
+func schemaFor(t reflect.Type) (*jsonSchema, error) {
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+func schemaForType(t reflect.Type, seen map[reflect.Type]bool) (*jsonSchema, error) {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	s := &jsonSchema{Nullable: nullable}
+	switch {
+	case t == timeType:
+		s.Type, s.Format = "string", "date-time"
+		return s, nil
+	case t == rawMessageType:
+		return s, nil
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Custom encodings cannot be described from the type.
+		return s, nil
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		s.Type = "string"
+		return s, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Interface:
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s.Type, s.Format = "string", "byte"
+			break
+		}
+		items, err := schemaForType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		s.Type, s.Items = "array", items
+		s.Nullable = s.Nullable || t.Kind() == reflect.Slice
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := schemaForType(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		s.Type, s.AdditionalProperties = "object", values
+		s.Nullable = true
+	case reflect.Struct:
+		if seen[t] {
+			return nil, fmt.Errorf("recursive type %s is not supported", t)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s.Type = "object"
+		s.Properties = map[string]*jsonSchema{}
+		s.AdditionalProperties = false
+		if err := addFields(s, t, seen); err != nil {
+			return nil, err
+		}
+		sort.Strings(s.Required)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+	return s, nil
+}
+
+func addFields(s *jsonSchema, t reflect.Type, seen map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addFields(s, ft, seen); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs, err := schemaForType(f.Type, seen)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if strings.Contains(opts, "string") && fs.Type != "" && fs.Type != "string" {
+			fs = &jsonSchema{Type: "string"}
+		}
+		fs.Description = f.Tag.Get("description")
+		if enum := f.Tag.Get("enum"); enum != "" {
+			for _, v := range strings.Split(enum, ",") {
+				fs.Enum = append(fs.Enum, v)
+			}
+		}
+
+		s.Properties[name] = fs
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// validate checks a decoded JSON value against the schema and returns the
+// first violation, located by a JSON pointer-like path.
+func (s *jsonSchema) validate(v any, path string) error {
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", pathOrRoot(path))
+	}
+
+	if len(s.Enum) > 0 {
+		str, _ := v.(string)
+		found := false
+		for _, e := range s.Enum {
+			if e == str {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: must be one of %v", pathOrRoot(path), s.Enum)
+		}
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return typeError(path, "a boolean", v)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return typeError(path, "an integer", v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return typeError(path, "a number", v)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return typeError(path, "a string", v)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: must be an RFC 3339 date-time", pathOrRoot(path))
+			}
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return typeError(path, "an array", v)
+		}
+		for i, item := range items {
+			if err := s.Items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return typeError(path, "an object", v)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", pathOrRoot(path), name)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ps, ok := s.Properties[k]
+			if !ok {
+				extra, _ := s.AdditionalProperties.(*jsonSchema)
+				if extra == nil {
+					if s.Properties != nil {
+						return fmt.Errorf("%s: unknown property %q", pathOrRoot(path), k)
+					}
+					continue
+				}
+				ps = extra
+			}
+			if err := ps.validate(obj[k], path+"/"+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func typeError(path, want string, got any) error {
+	return fmt.Errorf("%s: must be %s, got %s", pathOrRoot(path), want, jsonKind(got))
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
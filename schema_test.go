@@ -0,0 +1,146 @@
+package mix
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaInner struct {
+	Label string `json:"label"`
+}
+
+type schemaEmbedded struct {
+	Source string `json:"source"`
+}
+
+type schemaSample struct {
+	schemaEmbedded
+	Name     string          `json:"name" description:"Display name"`
+	Level    string          `json:"level" enum:"low,high"`
+	Count    int             `json:"count"`
+	Score    float64         `json:"score,omitempty"`
+	ID       int64           `json:"id,string"`
+	Note     *string         `json:"note"`
+	Tags     []string        `json:"tags"`
+	Items    []schemaInner   `json:"items,omitempty"`
+	Meta     map[string]int  `json:"meta,omitempty"`
+	At       time.Time       `json:"at"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+	Blob     []byte          `json:"blob,omitempty"`
+	Any      any             `json:"any,omitempty"`
+	Skipped  string          `json:"-"`
+	Untagged bool
+	Labels   map[string]string `json:"labels,omitempty"`
+	hidden   string
+}
+
+type schemaRecursive struct {
+	Next *schemaRecursive `json:"next"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	t.Parallel()
+	s, err := schemaFor(reflect.TypeOf(schemaSample{}))
+	require.NoError(t, err)
+
+	got, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["Untagged", "at", "count", "id", "level", "name", "source", "tags"],
+		"properties": {
+			"source": {"type": "string"},
+			"name": {"type": "string", "description": "Display name"},
+			"level": {"type": "string", "enum": ["low", "high"]},
+			"count": {"type": "integer"},
+			"score": {"type": "number"},
+			"id": {"type": "string"},
+			"note": {"type": "string"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"items": {"type": "array", "items": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["label"],
+				"properties": {"label": {"type": "string"}}
+			}},
+			"meta": {"type": "object", "additionalProperties": {"type": "integer"}},
+			"at": {"type": "string", "format": "date-time"},
+			"raw": {},
+			"blob": {"type": "string", "format": "byte"},
+			"any": {},
+			"Untagged": {"type": "boolean"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+		}
+	}`, string(got))
+}
+
+func TestSchemaFor_Unsupported(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		typ  reflect.Type
+	}{
+		{"recursive", reflect.TypeOf(schemaRecursive{})},
+		{"int map keys", reflect.TypeOf(map[int]string{})},
+		{"channel", reflect.TypeOf(make(chan int))},
+		{"func field", reflect.TypeOf(struct {
+			F func() `json:"f"`
+		}{})},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := schemaFor(tt.typ)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	t.Parallel()
+	s, err := schemaFor(reflect.TypeOf(schemaSample{}))
+	require.NoError(t, err)
+
+	valid := `{"source":"s","name":"n","level":"low","count":1,"id":"7","note":null,"tags":[],"at":"2026-01-01T00:00:00Z","Untagged":true`
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"valid", valid + `}`, ""},
+		{"optional fields", valid + `,"score":1.5,"items":[{"label":"x"}],"meta":{"a":1},"raw":[1],"any":"x"}`, ""},
+		{"not an object", `[]`, "/: must be an object, got array"},
+		{"null", `null`, "/: must not be null"},
+		{"missing required", `{"name":"n"}`, `/: missing required property "Untagged"`},
+		{"unknown property", valid + `,"extra":1}`, `/: unknown property "extra"`},
+		{"type mismatch", valid + `,"score":"high"}`, "/score: must be a number, got string"},
+		{"fractional integer", `{"source":"s","name":"n","level":"low","count":1.5,"id":"7","tags":[],"at":"2026-01-01T00:00:00Z","Untagged":true}`, "/count: must be an integer, got number"},
+		{"enum", `{"source":"s","name":"n","level":"mid","count":1,"id":"7","tags":[],"at":"2026-01-01T00:00:00Z","Untagged":true}`, "/level: must be one of [low high]"},
+		{"date-time", `{"source":"s","name":"n","level":"low","count":1,"id":"7","tags":[],"at":"yesterday","Untagged":true}`, "/at: must be an RFC 3339 date-time"},
+		{"nested item", valid + `,"items":[{"label":"x"},{"label":2}]}`, "/items/1/label: must be a string, got number"},
+		{"map value", valid + `,"meta":{"a":"b"}}`, "/meta/a: must be an integer, got string"},
+		{"null required non-pointer", `{"source":"s","name":null,"level":"low","count":1,"id":"7","tags":[],"at":"2026-01-01T00:00:00Z","Untagged":true}`, "/name: must not be null"},
+		{"null slice", `{"source":"s","name":"n","level":"low","count":1,"id":"7","tags":null,"at":"2026-01-01T00:00:00Z","Untagged":true}`, ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var v any
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &v))
+			err := s.validate(v, "")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/events"
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+// PermissionHandler decides a permission request raised while waiting for a
+// message. Returning true grants it and false denies it.
+type PermissionHandler func(ctx context.Context, req components.SSEPermissionEventData) (bool, error)
+
+// NotificationHandler answers a notification raised while waiting for a
+// message. Returning a nil response leaves the notification unanswered; other
+// responses are checked with ValidateNotificationResponse before being sent.
+type NotificationHandler func(ctx context.Context, n components.SSENotificationEventData) (*operations.RespondToNotificationRequestBody, error)
+
+// MessageUsage is the token usage and cost of an assistant message.
+type MessageUsage struct {
+	InputTokens         *int64
+	OutputTokens        *int64
+	CacheCreationTokens *int64
+	CacheReadTokens     *int64
+	Cost                *float64
+	Model               *string
+}
+
+// MessageResult is the outcome of SendMessageAndWait.
+type MessageResult struct {
+	events.Turn
+	SessionID string
+	// Usage of the assistant message, if it could be looked up once the turn
+	// completed
+	Usage *MessageUsage
+}
+
+type waitOptions struct {
+	timeout        time.Duration
+	onPermission   PermissionHandler
+	onNotification NotificationHandler
+	subscribeOpts  []SubscribeOption
+	skipUsage      bool
+	budget         *BudgetGuard
+}
+
+type WaitOption func(*waitOptions)
+
+// WithWaitTimeout bounds how long SendMessageAndWait waits for the turn to
+// complete. When it expires, processing is cancelled on the server.
+func WithWaitTimeout(timeout time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithPermissionHandler answers permission requests raised during the turn,
+// including those from subagents. Without one, requests stay pending.
+func WithPermissionHandler(handler PermissionHandler) WaitOption {
+	return func(o *waitOptions) {
+		o.onPermission = handler
+	}
+}
+
+// WithNotificationHandler answers notifications raised during the turn.
+func WithNotificationHandler(handler NotificationHandler) WaitOption {
+	return func(o *waitOptions) {
+		o.onNotification = handler
+	}
+}
+
+// WithWaitSubscribeOptions configures the stream subscription used to follow
+// the turn.
+func WithWaitSubscribeOptions(opts ...SubscribeOption) WaitOption {
+	return func(o *waitOptions) {
+		o.subscribeOpts = append(o.subscribeOpts, opts...)
+	}
+}
+
+// WithBudget enforces a session budget while waiting. Once a limit is
+// exceeded, processing is cancelled and the *BudgetExceededError is returned
+// with the partial turn.
+func WithBudget(guard *BudgetGuard) WaitOption {
+	return func(o *waitOptions) {
+		o.budget = guard
+	}
+}
+
+// WithoutUsage skips looking up the message usage after the turn completes.
+func WithoutUsage() WaitOption {
+	return func(o *waitOptions) {
+		o.skipUsage = true
+	}
+}
+
+// SendMessageAndWait sends a message and blocks until the agent's turn
+// completes. It subscribes to the session stream before sending, so no events
+// are missed, and returns the assembled turn once the root agent's complete
+// event arrives. If the timeout expires, or a handler or the budget check
+// fails first, processing is cancelled on the server and an
+// *apierrors.CancelError is returned along with the partial turn. The same
+// happens when ctx is done if the SDK was created with WithCancelOnContextDone
+// or the subscription has WithCancelOnDone.
+func (s *Messages) SendMessageAndWait(ctx context.Context, id string, requestBody operations.SendMessageRequestBody, opts ...WaitOption) (*MessageResult, error) {
+	var o waitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	callerCtx := ctx
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	sub, err := s.rootSDK.Streaming.Subscribe(ctx, id, o.subscribeOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("subscribing to session %s: %w", id, err)
+	}
+	defer sub.Close()
+
+	if _, err := s.SendMessage(ctx, id, requestBody); err != nil {
+		return nil, err
+	}
+
+	result := &MessageResult{SessionID: id}
+	turn, abandoned, err := s.waitForTurn(ctx, sub, o)
+	result.Turn = turn
+	if err != nil {
+		// A failed stream says nothing about the turn itself, which may still
+		// be running fine; only a turn nobody is waiting for is cancelled.
+		if abandoned && (callerCtx.Err() == nil || sub.opts.cancelOnDone) {
+			return result, s.cancelProcessing(ctx, id, err)
+		}
+		return result, err
+	}
+
+	if !o.skipUsage && turn.MessageID != nil {
+		result.Usage = s.lookupUsage(ctx, id, *turn.MessageID)
+	}
+
+	return result, nil
+}
+
+// waitForTurn follows the stream until the root turn started by the message
+// completes. The turn starts with the first root user_message_created event;
+// events before it belong to an earlier turn and are not assembled. The
+// subscription is opened just before sending, so that event is the message's
+// own, whatever the server made of its text. It reports whether the turn was
+// abandoned, because ctx is done or a handler or the budget check failed, as
+// opposed to the stream ending.
+func (s *Messages) waitForTurn(ctx context.Context, sub *Subscription, o waitOptions) (events.Turn, bool, error) {
+	assembler := events.NewAssembler()
+	started := false
+
+	for sub.Next() {
+		event := sub.Value()
+
+		switch {
+		case event.SSEPermissionEvent != nil && o.onPermission != nil:
+			if err := s.answerPermission(ctx, event.SSEPermissionEvent.Data, o.onPermission); err != nil {
+				return assembler.Snapshot(), true, err
+			}
+		case event.SSENotificationEvent != nil && o.onNotification != nil:
+			if err := s.answerNotification(ctx, event.SSENotificationEvent.Data, o.onNotification); err != nil {
+				return assembler.Snapshot(), true, err
+			}
+		case event.SSEUserMessageCreatedEvent != nil && events.ParentToolCallID(event) == nil:
+			started = true
+		}
+
+		if o.budget != nil {
+			// The guard cancels processing itself.
+			if err := o.budget.Observe(ctx, event); errors.Is(err, ErrBudgetExceeded) {
+				return assembler.Snapshot(), false, err
+			} else if err != nil {
+				return assembler.Snapshot(), true, fmt.Errorf("checking budget: %w", err)
+			}
+		}
+
+		if !started {
+			continue
+		}
+
+		assembler.Add(event)
+		if events.IsTurnComplete(event) {
+			return assembler.Snapshot(), false, nil
+		}
+	}
+
+	// The subscription reports its own cancel when it was asked to.
+	var cancelErr *apierrors.CancelError
+	if err := sub.Err(); errors.As(err, &cancelErr) {
+		return assembler.Snapshot(), false, err
+	}
+	if err := ctx.Err(); err != nil {
+		return assembler.Snapshot(), true, err
+	}
+	if err := sub.Err(); err != nil {
+		return assembler.Snapshot(), false, err
+	}
+	return assembler.Snapshot(), false, io.ErrUnexpectedEOF
+}
+
+func (s *Messages) answerPermission(ctx context.Context, req components.SSEPermissionEventData, handler PermissionHandler) error {
+	grant, err := handler(ctx, req)
+	if err != nil {
+		return fmt.Errorf("permission handler: %w", err)
+	}
+
+	if grant {
+		_, err = s.rootSDK.Permissions.GrantPermission(ctx, req.ID)
+	} else {
+		_, err = s.rootSDK.Permissions.DenyPermission(ctx, req.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("answering permission %s: %w", req.ID, err)
+	}
+	return nil
+}
+
+func (s *Messages) answerNotification(ctx context.Context, n components.SSENotificationEventData, handler NotificationHandler) error {
+	res, err := handler(ctx, n)
+	if err != nil {
+		return fmt.Errorf("notification handler: %w", err)
+	}
+	if res == nil {
+		return nil
+	}
+	if err := ValidateNotificationResponse(n, *res); err != nil {
+		return err
+	}
+
+	if _, err := s.rootSDK.Notifications.RespondToNotification(ctx, n.ID, *res); err != nil {
+		return fmt.Errorf("answering notification %s: %w", n.ID, err)
+	}
+	return nil
+}
+
+// lookupUsage finds the usage of a completed message. Failures are ignored:
+// the turn itself already succeeded.
+func (s *Messages) lookupUsage(ctx context.Context, id, messageID string) *MessageUsage {
+	res, err := s.GetSessionMessages(ctx, id)
+	if err != nil {
+		return nil
+	}
+
+	for _, msg := range res.BackendMessages {
+		if msg.ID == messageID {
+			return &MessageUsage{
+				InputTokens:         msg.InputTokens,
+				OutputTokens:        msg.OutputTokens,
+				CacheCreationTokens: msg.CacheCreationTokens,
+				CacheReadTokens:     msg.CacheReadTokens,
+				Cost:                msg.Cost,
+				Model:               msg.Model,
+			}
+		}
+	}
+	return nil
+}
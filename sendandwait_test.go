@@ -0,0 +1,148 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+)
+
+func TestSendMessageAndWait(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		// The tail of an earlier turn, then the message as the server
+		// normalized it.
+		a.content("earlier")
+		a.userMessage(strings.TrimSpace(text))
+		a.content("Hel")
+		a.push("permission", `{"action":"run","description":"Run ls","id":"p1","sessionId":"s1","toolName":"Bash","type":"permission","params":{"command":"ls"}}`)
+		a.content("lo")
+		a.complete("m1")
+	})
+
+	var asked []string
+	res, err := client.Messages.SendMessageAndWait(context.Background(), "s1", operations.SendMessageRequestBody{Text: "  hi  "},
+		WithWaitTimeout(5*time.Second),
+		WithoutUsage(),
+		WithPermissionHandler(func(ctx context.Context, req components.SSEPermissionEventData) (bool, error) {
+			asked = append(asked, req.ID)
+			return true, nil
+		}))
+	require.NoError(t, err)
+
+	assert.True(t, res.Done)
+	assert.Equal(t, "s1", res.SessionID)
+	assert.Equal(t, "hi", res.UserInput)
+	assert.Equal(t, "Hello", res.Content)
+	assert.Equal(t, String("m1"), res.MessageID)
+	assert.Equal(t, []string{"p1"}, asked)
+	assert.Equal(t, []string{"message:  hi  ", "permission:p1/grant"}, srv.received())
+}
+
+func TestSendMessageAndWait_Cancel(t *testing.T) {
+	t.Parallel()
+	boom := errors.New("boom")
+	tests := []struct {
+		name  string
+		opts  []WaitOption
+		cause error
+	}{
+		{
+			name:  "timeout",
+			opts:  []WaitOption{WithWaitTimeout(100 * time.Millisecond)},
+			cause: context.DeadlineExceeded,
+		},
+		{
+			name: "handler error",
+			opts: []WaitOption{WithPermissionHandler(func(context.Context, components.SSEPermissionEventData) (bool, error) {
+				return false, boom
+			})},
+			cause: boom,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, client := newAgentServer(t, func(a *agentServer, text string) {
+				a.userMessage(text)
+				a.content("working")
+				a.push("permission", `{"action":"run","description":"Run ls","id":"p1","sessionId":"s1","toolName":"Bash","type":"permission"}`)
+			})
+
+			res, err := client.Messages.SendMessageAndWait(context.Background(), "s1", operations.SendMessageRequestBody{Text: "hi"}, tt.opts...)
+			var cancelErr *apierrors.CancelError
+			require.ErrorAs(t, err, &cancelErr)
+			assert.True(t, cancelErr.Acknowledged)
+			assert.ErrorIs(t, err, tt.cause)
+			assert.Equal(t, "working", res.Content)
+			assert.False(t, res.Done)
+			assert.Contains(t, srv.received(), "cancel")
+		})
+	}
+}
+
+func TestSendMessageAndWait_CallerContextDoesNotCancel(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.Messages.SendMessageAndWait(ctx, "s1", operations.SendMessageRequestBody{Text: "hi"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, srv.received(), "cancel")
+}
+
+func TestSendMessageAndWait_Budget(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		a.push("tool_execution_start", `{"toolCallId":"t1","toolName":"Bash","progress":"running","type":"tool_execution_start"}`)
+		a.push("tool_execution_start", `{"toolCallId":"t2","toolName":"Bash","progress":"running","type":"tool_execution_start"}`)
+		a.complete("m1")
+	})
+
+	guard, err := client.Sessions.NewBudgetGuard(context.Background(), "s1", Budget{MaxToolCalls: 1})
+	require.NoError(t, err)
+
+	_, err = client.Messages.SendMessageAndWait(context.Background(), "s1", operations.SendMessageRequestBody{Text: "hi"},
+		WithWaitTimeout(5*time.Second), WithBudget(guard))
+	var exceeded *BudgetExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, BudgetToolCalls, exceeded.Limit)
+	assert.Equal(t, int64(2), exceeded.Usage.ToolCalls)
+	assert.Contains(t, srv.received(), "cancel")
+}
+
+func TestSendMessageAndWait_BudgetCheckFails(t *testing.T) {
+	t.Parallel()
+	srv, client := newAgentServer(t, func(a *agentServer, text string) {
+		a.userMessage(text)
+		a.mu.Lock()
+		a.session = ""
+		a.mu.Unlock()
+		a.complete("m1")
+	})
+
+	guard, err := client.Sessions.NewBudgetGuard(context.Background(), "s1", Budget{MaxCost: 1})
+	require.NoError(t, err)
+
+	_, err = client.Messages.SendMessageAndWait(context.Background(), "s1", operations.SendMessageRequestBody{Text: "hi"},
+		WithWaitTimeout(5*time.Second), WithBudget(guard))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrBudgetExceeded)
+	assert.Contains(t, err.Error(), "checking budget")
+	var errRes *apierrors.ErrorResponse
+	assert.ErrorAs(t, err, &errRes)
+	assert.Contains(t, srv.received(), "cancel")
+}
@@ -1,5 +1,3 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package mix
 
 import (
@@ -213,13 +211,23 @@ func (s *Streaming) StreamEvents(ctx context.Context, sessionID string, lastEven
 
 		switch {
 		case utils.MatchContentType(httpRes.Header.Get("Content-Type"), `text/event-stream`):
+			streamOpts := []stream.Option{
+				stream.WithHeartbeatEvent(string(components.SSEEventStreamTypeHeartbeat)),
+				stream.WithDecoder(components.DecodeSSEEvent),
+			}
+			if s.sdkConfiguration.MaxEventSize != nil {
+				streamOpts = append(streamOpts, stream.WithMaxEventSize(*s.sdkConfiguration.MaxEventSize))
+			}
+			if s.sdkConfiguration.StreamIdleTimeout != nil {
+				streamOpts = append(streamOpts, stream.WithIdleTimeout(*s.sdkConfiguration.StreamIdleTimeout))
+			}
 			out := stream.NewEventStream(ctx, httpRes.Body, func(se []byte) (components.SSEEventStream, error) {
 				var e components.SSEEventStream
 				if err := utils.UnmarshalJsonFromResponseBody(bytes.NewBuffer(se), &e, ""); err != nil {
 					return components.SSEEventStream{}, err
 				}
 				return e, nil
-			}, "")
+			}, "", streamOpts...)
 			res.SSEEventStream = out
 		default:
 			rawBody, err := utils.ConsumeRawBody(httpRes)
@@ -0,0 +1,479 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/recreate-run/mix-go-sdk/internal/utils"
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/models/components"
+	"github.com/recreate-run/mix-go-sdk/models/operations"
+	"github.com/recreate-run/mix-go-sdk/retry"
+	"github.com/recreate-run/mix-go-sdk/types/stream"
+)
+
+// ConnectionState describes the lifecycle of the SSE connection behind a
+// Subscription.
+type ConnectionState string
+
+const (
+	ConnectionStateConnecting   ConnectionState = "connecting"
+	ConnectionStateConnected    ConnectionState = "connected"
+	ConnectionStateReconnecting ConnectionState = "reconnecting"
+	ConnectionStateClosed       ConnectionState = "closed"
+)
+
+// ConnectionStateHandler is called on every connection state transition. err
+// carries the failure that caused a reconnect or close, if any.
+type ConnectionStateHandler func(state ConnectionState, err error)
+
+type subscribeOptions struct {
+	lastEventID   *string
+	backoff       retry.BackoffStrategy
+	onStateChange ConnectionStateHandler
+	streamOpts    []operations.Option
+	failOnError   bool
+	cancelOnDone  bool
+}
+
+type SubscribeOption func(*subscribeOptions)
+
+// WithLastEventID resumes the subscription after the given event ID instead
+// of starting from the live edge of the stream.
+func WithLastEventID(id string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.lastEventID = &id
+	}
+}
+
+// WithReconnectBackoff overrides the backoff used between reconnection
+// attempts. MaxElapsedTime bounds how long a single outage may last before the
+// subscription gives up.
+func WithReconnectBackoff(backoff retry.BackoffStrategy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.backoff = backoff
+	}
+}
+
+// WithConnectionStateHandler registers a handler for connection state changes.
+func WithConnectionStateHandler(handler ConnectionStateHandler) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.onStateChange = handler
+	}
+}
+
+// WithStreamOptions passes operation options through to every underlying
+// StreamEvents call.
+func WithStreamOptions(opts ...operations.Option) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.streamOpts = append(o.streamOpts, opts...)
+	}
+}
+
+// WithErrorEventsAsErrors ends the subscription with an
+// *apierrors.StreamError when the root agent reports an error the server will
+// not retry. Temporary error events are still yielded as values.
+func WithErrorEventsAsErrors() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.failOnError = true
+	}
+}
+
+// WithCancelOnDone cancels processing of the session on the server when ctx
+// is canceled or its deadline passes, as WithCancelOnContextDone does for the
+// whole SDK. Err then returns an *apierrors.CancelError. Close does not cancel.
+func WithCancelOnDone() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.cancelOnDone = true
+	}
+}
+
+// Subscription is a resumable event stream for a session. When the underlying
+// connection drops it reconnects with backoff, passing the last seen event ID,
+// and keeps yielding events through the same Next/Value loop.
+type Subscription struct {
+	streaming *Streaming
+	sessionID string
+	opts      subscribeOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	current     *stream.EventStream[components.SSEEventStream]
+	state       ConnectionState
+	lastEventID *string
+	retryDelay  *time.Duration
+	inNext      bool
+	closed      bool
+
+	val *components.SSEEventStream
+	err error
+}
+
+// Subscribe opens a resumable event stream for a session. It returns once the
+// first connection is established, or with an error if the server rejects the
+// subscription or the reconnect budget is exhausted.
+func (s *Streaming) Subscribe(ctx context.Context, sessionID string, opts ...SubscribeOption) (*Subscription, error) {
+	o := subscribeOptions{
+		backoff: retry.BackoffStrategy{
+			InitialInterval: 500,
+			MaxInterval:     60000,
+			Exponent:        1.5,
+			MaxElapsedTime:  600000,
+		},
+		cancelOnDone: s.sdkConfiguration.CancelOnContextDone,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// The subscription owns reconnection, so the per-request retry loop is
+	// disabled unless the caller explicitly asks for it.
+	o.streamOpts = append([]operations.Option{operations.WithRetries(retry.Config{Strategy: "none"})}, o.streamOpts...)
+
+	subCtx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		streaming:   s,
+		sessionID:   sessionID,
+		opts:        o,
+		ctx:         subCtx,
+		cancel:      cancel,
+		lastEventID: o.lastEventID,
+	}
+
+	if err := sub.connect(ConnectionStateConnecting, nil); err != nil {
+		sub.fail(err)
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Next waits for the next event, reconnecting transparently if the connection
+// is lost. It returns false once the subscription is closed, its context is
+// done, or reconnection fails; Err then reports the cause.
+func (s *Subscription) Next() bool {
+	s.mu.Lock()
+	if s.closed || s.err != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.inNext = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inNext = false
+		if s.closed && s.current != nil {
+			s.current.Close()
+			s.current = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		es := s.current
+		s.mu.Unlock()
+		if es == nil {
+			return false
+		}
+
+		if es.Next() {
+			s.mu.Lock()
+			if id := es.LastEventID(); id != nil {
+				s.lastEventID = id
+			}
+			s.mu.Unlock()
+
+			v := es.Value()
+			if v == nil {
+				continue
+			}
+			// The RetryAfter of an error event is about the model call the
+			// server retries, not about this connection, so it does not
+			// affect reconnects.
+			if v.SSEErrorEvent != nil && v.SSEErrorEvent.Data.ParentToolCallID == nil {
+				streamErr := apierrors.NewStreamError(v.SSEErrorEvent.Data)
+				if s.opts.failOnError && !streamErr.Temporary() {
+					s.fail(streamErr)
+					return false
+				}
+			}
+			s.val = v
+			return true
+		}
+
+		cause := es.Err()
+		if d := es.RetryInterval(); d != nil {
+			s.setRetryDelay(*d)
+		}
+		s.mu.Lock()
+		es.Close()
+		s.current = nil
+		s.mu.Unlock()
+
+		if s.isClosed() {
+			return false
+		}
+
+		if !s.shouldReconnect(cause) {
+			s.fail(cause)
+			return false
+		}
+
+		if err := s.connect(ConnectionStateReconnecting, cause); err != nil {
+			s.fail(err)
+			return false
+		}
+	}
+}
+
+// Value returns the event produced by the most recent call to Next.
+func (s *Subscription) Value() *components.SSEEventStream {
+	return s.val
+}
+
+// Err returns the error that ended the subscription, if any. It is nil after
+// an explicit Close.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// LastEventID returns the ID of the most recent event received, which is sent
+// as Last-Event-ID when reconnecting.
+func (s *Subscription) LastEventID() *string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventID
+}
+
+// State returns the current connection state.
+func (s *Subscription) State() ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Stats returns liveness statistics for the current connection. They reset on
+// every reconnect.
+func (s *Subscription) Stats() stream.Stats {
+	s.mu.Lock()
+	es := s.current
+	s.mu.Unlock()
+	if es == nil {
+		return stream.Stats{}
+	}
+	return es.Stats()
+}
+
+// Close stops the subscription and releases the underlying connection. It is
+// safe to call from any goroutine, including while Next is blocked.
+func (s *Subscription) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if !s.inNext && s.current != nil {
+		err = s.current.Close()
+		s.current = nil
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	s.setState(ConnectionStateClosed, nil)
+	return err
+}
+
+func (s *Subscription) connect(state ConnectionState, cause error) error {
+	start := time.Now()
+	maxElapsedTime := time.Duration(s.opts.backoff.MaxElapsedTime) * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		s.setState(state, cause)
+
+		// A dropped stream is reopened after the delay advertised by its
+		// retry field, if any, as the SSE reconnection time requires. Later
+		// attempts back off as usual.
+		if attempt == 0 && state == ConnectionStateReconnecting {
+			if d := s.takeRetryDelay(); d > 0 {
+				if err := s.sleep(d); err != nil {
+					return err
+				}
+			}
+		}
+
+		res, err := s.streaming.StreamEvents(s.ctx, s.sessionID, s.LastEventID(), s.opts.streamOpts...)
+		if err == nil && res.SSEEventStream == nil {
+			err = errors.New("stream response did not contain an event stream")
+		}
+		if err == nil {
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				res.SSEEventStream.Close()
+				return nil
+			}
+			s.current = res.SSEEventStream
+			s.retryDelay = nil
+			s.mu.Unlock()
+
+			s.setState(ConnectionStateConnected, nil)
+			return nil
+		}
+
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		if !isRetryableConnectError(err) || time.Since(start) >= maxElapsedTime {
+			return err
+		}
+
+		cause = err
+		state = ConnectionStateReconnecting
+
+		delay, ok := retryAfterHeader(err)
+		if !ok {
+			delay = utils.NextInterval(&s.opts.backoff, attempt)
+		}
+		if err := s.sleep(delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Subscription) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (s *Subscription) setRetryDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retryDelay = &d
+}
+
+// takeRetryDelay returns the reconnection delay advertised by the retry field
+// of the stream that just dropped, and clears it so that it applies to one
+// reconnect only.
+func (s *Subscription) takeRetryDelay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retryDelay == nil {
+		return 0
+	}
+	d := *s.retryDelay
+	s.retryDelay = nil
+	return d
+}
+
+func (s *Subscription) shouldReconnect(cause error) bool {
+	if s.ctx.Err() != nil {
+		return false
+	}
+
+	var decodeErr *stream.DecodeError
+	return !errors.As(cause, &decodeErr)
+}
+
+func (s *Subscription) fail(err error) {
+	if s.ctx.Err() != nil {
+		err = s.ctx.Err()
+		// Only the caller's context can be done before the subscription
+		// cancels its own, unless it was closed.
+		if s.opts.cancelOnDone && !s.isClosed() {
+			err = s.streaming.rootSDK.Messages.cancelProcessing(s.ctx, s.sessionID, err)
+		}
+	}
+
+	s.mu.Lock()
+	if s.err == nil && !s.closed {
+		s.err = err
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	s.setState(ConnectionStateClosed, err)
+}
+
+func (s *Subscription) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Subscription) setState(state ConnectionState, err error) {
+	s.mu.Lock()
+	if s.state == ConnectionStateClosed || (s.state == state && err == nil) {
+		s.mu.Unlock()
+		return
+	}
+	s.state = state
+	s.mu.Unlock()
+
+	if s.opts.onStateChange != nil {
+		s.opts.onStateChange(state, err)
+	}
+}
+
+// retryAfterHeader returns the delay requested by the Retry-After header of a
+// failed connection attempt, in either delta-seconds or HTTP-date form.
+func retryAfterHeader(err error) (time.Duration, bool) {
+	var res *http.Response
+	var apiErr *apierrors.APIError
+	var errRes *apierrors.ErrorResponse
+	switch {
+	case errors.As(err, &apiErr):
+		res = apiErr.RawResponse
+	case errors.As(err, &errRes):
+		res = errRes.HTTPMeta.Response
+	}
+	if res == nil {
+		return 0, false
+	}
+
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func isRetryableConnectError(err error) bool {
+	var apiErr *apierrors.APIError
+	if errors.As(err, &apiErr) {
+		return utils.MatchStatusCodes([]string{"408", "429", "5XX"}, apiErr.StatusCode)
+	}
+
+	var errRes *apierrors.ErrorResponse
+	if errors.As(err, &errRes) {
+		if res := errRes.HTTPMeta.Response; res != nil {
+			return utils.MatchStatusCodes([]string{"408", "429", "5XX"}, res.StatusCode)
+		}
+		return false
+	}
+
+	return true
+}
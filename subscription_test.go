@@ -0,0 +1,194 @@
+package mix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/recreate-run/mix-go-sdk/models/apierrors"
+	"github.com/recreate-run/mix-go-sdk/retry"
+)
+
+// sseConn scripts one connection to the stream endpoint. A non-zero status
+// fails the connection; otherwise frames are written and the stream is closed,
+// or held open until the client leaves when hold is set.
+type sseConn struct {
+	status int
+	frames string
+	hold   bool
+}
+
+// sseServer serves the scripted connections in order, holding the last one
+// open once the script is exhausted, and records when each one was made.
+type sseServer struct {
+	mu           sync.Mutex
+	conns        []sseConn
+	lastEventIDs []string
+	times        []time.Time
+}
+
+func (s *sseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	n := len(s.times)
+	s.times = append(s.times, time.Now())
+	s.lastEventIDs = append(s.lastEventIDs, r.Header.Get("Last-Event-ID"))
+	conn := sseConn{hold: true}
+	if n < len(s.conns) {
+		conn = s.conns[n]
+	}
+	s.mu.Unlock()
+
+	if conn.status != 0 {
+		w.WriteHeader(conn.status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, conn.frames)
+	w.(http.Flusher).Flush()
+	if conn.hold {
+		<-r.Context().Done()
+	}
+}
+
+func (s *sseServer) connections() ([]time.Time, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.times...), append([]string(nil), s.lastEventIDs...)
+}
+
+func contentFrame(id, text string) string {
+	return fmt.Sprintf("id: %s\nevent: content\ndata: {\"content\":%q,\"type\":\"content\"}\n\n", id, text)
+}
+
+func errorFrame(id, data string) string {
+	return fmt.Sprintf("id: %s\nevent: error\ndata: %s\n\n", id, data)
+}
+
+// fastBackoff keeps reconnects in tests well below the delays they measure.
+var fastBackoff = WithReconnectBackoff(retry.BackoffStrategy{
+	InitialInterval: 1,
+	MaxInterval:     10,
+	Exponent:        1.5,
+	MaxElapsedTime:  5000,
+})
+
+func subscribe(t *testing.T, srv *sseServer, opts ...SubscribeOption) *Subscription {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	sub, err := New(ts.URL).Streaming.Subscribe(ctx, "s1", append([]SubscribeOption{fastBackoff}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { sub.Close() })
+	return sub
+}
+
+func nextContent(t *testing.T, sub *Subscription) string {
+	t.Helper()
+	require.True(t, sub.Next(), "Next: %v", sub.Err())
+	require.NotNil(t, sub.Value().SSEContentEvent)
+	return sub.Value().SSEContentEvent.Data.Content
+}
+
+func TestSubscription_Reconnect(t *testing.T) {
+	t.Parallel()
+	srv := &sseServer{conns: []sseConn{
+		{frames: contentFrame("1", "a")},
+		{status: http.StatusServiceUnavailable},
+		{frames: contentFrame("2", "b"), hold: true},
+	}}
+
+	var mu sync.Mutex
+	var states []ConnectionState
+	sub := subscribe(t, srv, WithLastEventID("0"), WithConnectionStateHandler(func(state ConnectionState, err error) {
+		mu.Lock()
+		states = append(states, state)
+		mu.Unlock()
+	}))
+
+	assert.Equal(t, "a", nextContent(t, sub))
+	assert.Equal(t, "b", nextContent(t, sub))
+	assert.Equal(t, "2", *sub.LastEventID())
+	assert.Equal(t, ConnectionStateConnected, sub.State())
+
+	require.NoError(t, sub.Close())
+	assert.False(t, sub.Next())
+	assert.NoError(t, sub.Err())
+
+	_, ids := srv.connections()
+	assert.Equal(t, []string{"0", "1", "1"}, ids)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []ConnectionState{
+		ConnectionStateConnecting, ConnectionStateConnected,
+		ConnectionStateReconnecting, ConnectionStateReconnecting, ConnectionStateConnected,
+		ConnectionStateClosed,
+	}, states)
+}
+
+func TestSubscription_RetryHintAppliesOnce(t *testing.T) {
+	t.Parallel()
+	const hint = 400 * time.Millisecond
+	srv := &sseServer{conns: []sseConn{
+		{frames: fmt.Sprintf("retry: %d\n", hint.Milliseconds()) + contentFrame("1", "a")},
+		{status: http.StatusServiceUnavailable},
+		{frames: contentFrame("2", "b")},
+		{frames: contentFrame("3", "c"), hold: true},
+	}}
+	sub := subscribe(t, srv)
+
+	assert.Equal(t, "a", nextContent(t, sub))
+	assert.Equal(t, "b", nextContent(t, sub))
+	assert.Equal(t, "c", nextContent(t, sub))
+
+	times, _ := srv.connections()
+	require.Len(t, times, 4)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), hint, "the hint delays the next reconnect")
+	assert.Less(t, times[2].Sub(times[1]), hint/2, "a failed reconnect backs off instead")
+	assert.Less(t, times[3].Sub(times[2]), hint/2, "the hint is reset once connected")
+}
+
+func TestSubscription_ErrorRetryAfterDoesNotDelayReconnect(t *testing.T) {
+	t.Parallel()
+	srv := &sseServer{conns: []sseConn{
+		{frames: errorFrame("1", `{"error":"rate limited","type":"llm","retryAfter":5000}`)},
+		{frames: contentFrame("2", "a"), hold: true},
+	}}
+	sub := subscribe(t, srv, WithErrorEventsAsErrors())
+
+	require.True(t, sub.Next(), "temporary errors are yielded: %v", sub.Err())
+	require.NotNil(t, sub.Value().SSEErrorEvent)
+	start := time.Now()
+	assert.Equal(t, "a", nextContent(t, sub))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSubscription_ErrorEventsAsErrors(t *testing.T) {
+	t.Parallel()
+	srv := &sseServer{conns: []sseConn{
+		{frames: errorFrame("1", `{"error":"subagent failed","parentToolCallId":"t1"}`) +
+			errorFrame("2", `{"error":"context too long","type":"llm"}`) +
+			contentFrame("3", "unreachable"), hold: true},
+	}}
+	sub := subscribe(t, srv, WithErrorEventsAsErrors())
+
+	require.True(t, sub.Next(), "subagent errors are yielded: %v", sub.Err())
+	require.False(t, sub.Next())
+
+	var streamErr *apierrors.StreamError
+	require.True(t, errors.As(sub.Err(), &streamErr))
+	assert.Equal(t, "context too long", streamErr.Message)
+	assert.False(t, streamErr.Temporary())
+	assert.Equal(t, ConnectionStateClosed, sub.State())
+}
@@ -0,0 +1,181 @@
+package stream
+
+import "sync"
+
+// OverflowPolicy decides what Events does when its buffer is full because the
+// consumer is slower than the network.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock stops reading from the network until there is room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered event to make room.
+	OverflowDropOldest
+	// OverflowCoalesce merges the new event into the newest buffered one using
+	// the function given to WithCoalesce, and blocks when they cannot be merged.
+	OverflowCoalesce
+)
+
+// DefaultChannelBuffer is the number of events Events buffers unless
+// configured otherwise with WithBuffer.
+const DefaultChannelBuffer = 64
+
+type channelOptions[T any] struct {
+	buffer   int
+	policy   OverflowPolicy
+	coalesce func(last, next T) (T, bool)
+}
+
+// ChannelOption configures Events.
+type ChannelOption[T any] func(*channelOptions[T])
+
+// WithBuffer sets how many events are buffered between the network reader and
+// the consumer. Values below one are treated as one.
+func WithBuffer[T any](n int) ChannelOption[T] {
+	return func(o *channelOptions[T]) {
+		o.buffer = n
+	}
+}
+
+// WithOverflow selects the policy applied when the buffer is full.
+func WithOverflow[T any](policy OverflowPolicy) ChannelOption[T] {
+	return func(o *channelOptions[T]) {
+		o.policy = policy
+	}
+}
+
+// WithCoalesce selects OverflowCoalesce with the given merge function. merge
+// returns the combined event and true, or false if the events cannot be
+// merged.
+func WithCoalesce[T any](merge func(last, next T) (T, bool)) ChannelOption[T] {
+	return func(o *channelOptions[T]) {
+		o.policy = OverflowCoalesce
+		o.coalesce = merge
+	}
+}
+
+// Events reads the stream on a separate goroutine and delivers its events on
+// the returned channel, which is closed when the stream ends or is closed, or
+// its context is done, once the read in progress has returned. Err reports
+// why once the channel is closed. Next must not be called after Events.
+func (es *EventStream[T]) Events(opts ...ChannelOption[T]) <-chan T {
+	o := channelOptions[T]{buffer: DefaultChannelBuffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.buffer < 1 {
+		o.buffer = 1
+	}
+	if o.policy == OverflowCoalesce && o.coalesce == nil {
+		o.policy = OverflowBlock
+	}
+
+	q := &eventQueue[T]{opts: o, onDrop: es.monitor.drop}
+	q.cond = sync.NewCond(&q.mu)
+	out := make(chan T)
+	read := make(chan struct{})
+
+	go func() {
+		defer close(read)
+		defer q.close()
+		for es.Next() {
+			if v := es.Value(); v != nil && !q.push(*v) {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		// Err is only final once the reader's last Next returned.
+		defer func() { <-read }()
+		// Wake a reader blocked on a full queue when delivery stops early.
+		defer q.close()
+		for {
+			v, ok := q.pop()
+			if !ok {
+				return
+			}
+			select {
+			case out <- v:
+			case <-es.done:
+				return
+			case <-es.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+type eventQueue[T any] struct {
+	opts   channelOptions[T]
+	onDrop func()
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []T
+	closed bool
+}
+
+// push adds an event, applying the overflow policy. It returns false once the
+// queue has been closed.
+func (q *eventQueue[T]) push(v T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && len(q.items) >= q.opts.buffer {
+		switch q.opts.policy {
+		case OverflowDropOldest:
+			var zero T
+			q.items[0] = zero
+			q.items = q.items[1:]
+			q.onDrop()
+			continue
+		case OverflowCoalesce:
+			last := len(q.items) - 1
+			if merged, ok := q.opts.coalesce(q.items[last], v); ok {
+				q.items[last] = merged
+				return true
+			}
+		}
+		q.cond.Wait()
+	}
+	if q.closed {
+		return false
+	}
+
+	q.items = append(q.items, v)
+	q.cond.Broadcast()
+	return true
+}
+
+// pop removes the oldest event, waiting until one is available. It returns
+// false once the queue is closed and drained.
+func (q *eventQueue[T]) pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	v := q.items[0]
+	var zero T
+	q.items[0] = zero
+	q.items = q.items[1:]
+	q.cond.Broadcast()
+	return v, true
+}
+
+func (q *eventQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,311 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// DefaultMaxEventSize is the largest event, in bytes, that an EventStream
+// accepts unless configured otherwise with WithMaxEventSize.
+const DefaultMaxEventSize = 16 << 20
+
+// ErrEventTooLarge is returned when a single event exceeds the configured
+// maximum event size.
+var ErrEventTooLarge = errors.New("stream: event exceeds maximum size")
+
+const initialBufferSize = 4096
+
+var bom = []byte("\uFEFF")
+
+// frame is one dispatched block of the event stream, before decoding. data
+// holds the concatenated data lines joined by "\n".
+type frame struct {
+	id      *string
+	event   *string
+	data    []byte
+	retry   *int64
+	publish bool
+}
+
+// decoder splits a text/event-stream body into frames. It handles LF, CR and
+// CRLF line endings and grows its buffer as needed up to maxEventSize, so
+// events are never truncated by a fixed token size.
+type decoder struct {
+	r            io.Reader
+	maxEventSize int
+	// monitor, if set, is told when the decoder waits on r
+	monitor *monitor
+
+	buf        []byte
+	start, end int
+	err        error
+	skipLF     bool
+	first      bool
+
+	lastID *string
+	data   []byte
+}
+
+func newDecoder(r io.Reader, maxEventSize int) *decoder {
+	return &decoder{
+		r:            r,
+		maxEventSize: maxEventSize,
+		buf:          make([]byte, initialBufferSize),
+		first:        true,
+	}
+}
+
+// next returns the next frame in the stream. It returns io.EOF once the
+// stream is exhausted.
+func (d *decoder) next() (*frame, error) {
+	var (
+		f    frame
+		seen bool
+		size int
+	)
+	d.data = d.data[:0]
+	hasData := false
+
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if err == io.EOF && seen {
+				break
+			}
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if seen {
+				break
+			}
+			continue
+		}
+
+		seen = true
+		size += len(line)
+		if d.maxEventSize > 0 && size > d.maxEventSize {
+			return nil, ErrEventTooLarge
+		}
+
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+
+		switch string(field) {
+		case "id":
+			f.publish = true
+			if bytes.IndexByte(value, 0) < 0 {
+				id := string(value)
+				d.lastID = &id
+			}
+		case "event":
+			f.publish = true
+			name := string(value)
+			f.event = &name
+		case "retry":
+			if r, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				f.publish = true
+				f.retry = &r
+			}
+		case "data":
+			f.publish = true
+			if hasData {
+				d.data = append(d.data, '\n')
+			}
+			d.data = append(d.data, value...)
+			hasData = true
+		}
+	}
+
+	f.id = d.lastID
+	f.data = d.data
+
+	return &f, nil
+}
+
+// readLine returns the next line without its terminator. The returned slice is
+// only valid until the next call.
+func (d *decoder) readLine() ([]byte, error) {
+	for {
+		if d.start < d.end {
+			if d.skipLF {
+				d.skipLF = false
+				if d.buf[d.start] == '\n' {
+					d.start++
+					continue
+				}
+			}
+
+			if d.first {
+				if d.end-d.start < len(bom) && d.err == nil {
+					if err := d.fill(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				d.first = false
+				if bytes.HasPrefix(d.buf[d.start:d.end], bom) {
+					d.start += len(bom)
+					continue
+				}
+			}
+
+			avail := d.buf[d.start:d.end]
+			if i := indexLineEnd(avail); i >= 0 {
+				line := avail[:i]
+				d.start += i + 1
+				if avail[i] == '\r' {
+					d.skipLF = true
+				}
+				return line, nil
+			}
+		}
+
+		if d.err != nil {
+			if d.start < d.end {
+				line := d.buf[d.start:d.end]
+				d.start = d.end
+				return line, nil
+			}
+			return nil, d.err
+		}
+
+		if err := d.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fill reads more data into the buffer, compacting or growing it first.
+func (d *decoder) fill() error {
+	if d.start > 0 {
+		n := copy(d.buf, d.buf[d.start:d.end])
+		d.start, d.end = 0, n
+	}
+
+	if d.end == len(d.buf) {
+		if d.maxEventSize > 0 && len(d.buf) > d.maxEventSize {
+			return ErrEventTooLarge
+		}
+		grown := make([]byte, 2*len(d.buf))
+		copy(grown, d.buf[:d.end])
+		d.buf = grown
+	}
+
+	if d.monitor != nil {
+		d.monitor.reading()
+	}
+	n, err := d.r.Read(d.buf[d.end:])
+	if d.monitor != nil {
+		d.monitor.read()
+	}
+	d.end += n
+	if err != nil {
+		d.err = err
+	}
+
+	return nil
+}
+
+// serverEvent converts f into a ServerEvent, copying the data so the result
+// outlives the decoder's buffers.
+func (f *frame) serverEvent(quoteData bool) ServerEvent {
+	e := ServerEvent{ID: f.id, Event: f.event, Retry: f.retry}
+	if quoteData {
+		e.Data = appendJSONString(nil, string(f.data))
+	} else if len(f.data) > 0 {
+		e.Data = append(json.RawMessage(nil), f.data...)
+	}
+	return e
+}
+
+func indexLineEnd(b []byte) int {
+	for i, c := range b {
+		if c == '\n' || c == '\r' {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendEnvelope encodes f as the JSON object consumed by an EventStream
+// unmarshaller. When quoteData is set the data field is encoded as a JSON
+// string, otherwise it is embedded verbatim as a JSON value.
+func appendEnvelope(dst []byte, f *frame, quoteData bool) []byte {
+	dst = append(dst, '{')
+	sep := false
+	if f.id != nil {
+		dst = append(dst, `"id":`...)
+		dst = appendJSONString(dst, *f.id)
+		sep = true
+	}
+	if f.event != nil {
+		if sep {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"event":`...)
+		dst = appendJSONString(dst, *f.event)
+		sep = true
+	}
+	if len(f.data) > 0 || quoteData {
+		if sep {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"data":`...)
+		if quoteData {
+			dst = appendJSONString(dst, string(f.data))
+		} else {
+			dst = append(dst, f.data...)
+		}
+		sep = true
+	}
+	if f.retry != nil {
+		if sep {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"retry":`...)
+		dst = strconv.AppendInt(dst, *f.retry, 10)
+	}
+	return append(dst, '}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+		}
+		start = i + 1
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
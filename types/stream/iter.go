@@ -0,0 +1,26 @@
+//go:build go1.23
+
+package stream
+
+import "iter"
+
+// All returns an iterator over the stream's events for use with range. If the
+// stream fails, the final iteration yields the zero value and the error.
+// Breaking out of the loop leaves the stream open; it must still be closed.
+func (es *EventStream[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for es.Next() {
+			v := es.Value()
+			if v == nil {
+				continue
+			}
+			if !yield(*v, nil) {
+				return
+			}
+		}
+		if err := es.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
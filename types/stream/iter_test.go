@@ -0,0 +1,40 @@
+//go:build go1.23
+
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventStream_All(t *testing.T) {
+	t.Parallel()
+	type numericEvent struct {
+		Data int `json:"data"`
+	}
+	unmarshal := func(se []byte) (numericEvent, error) {
+		var v numericEvent
+		err := json.Unmarshal(se, &v)
+		return v, err
+	}
+
+	es := NewEventStream(context.Background(), strings.NewReader(numberedBody(3)+"data: x\n"), unmarshal, "")
+	defer es.Close()
+
+	var got []int
+	var err error
+	for e, iterErr := range es.All() {
+		if iterErr != nil {
+			err = iterErr
+			break
+		}
+		got = append(got, e.Data)
+	}
+	assert.Equal(t, []int{1, 2, 3}, got)
+	var decodeErr *DecodeError
+	assert.ErrorAs(t, err, &decodeErr)
+}
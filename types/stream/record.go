@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordedFrame is one event of a JSONL stream recording.
+type RecordedFrame struct {
+	// Milliseconds between opening the stream and receiving the event
+	Offset int64   `json:"t"`
+	ID     *string `json:"id,omitempty"`
+	Event  *string `json:"event,omitempty"`
+	Data   string  `json:"data"`
+	Retry  *int64  `json:"retry,omitempty"`
+}
+
+// RecordFormat selects the file format written by Record.
+type RecordFormat int
+
+const (
+	// RecordJSONL writes one RecordedFrame per line.
+	RecordJSONL RecordFormat = iota
+	// RecordSSE writes the frames as text/event-stream, each preceded by a
+	// ": t=<ms>" comment carrying its receive offset.
+	RecordSSE
+)
+
+type recorder struct {
+	w      io.Writer
+	format RecordFormat
+	buf    []byte
+}
+
+// Record tees every event received from now on to w, with its receive offset
+// since the stream was opened. It should be called before the first Next. A
+// failed write ends the stream with the write error.
+func (es *EventStream[T]) Record(w io.Writer, format RecordFormat) {
+	es.recorder = &recorder{w: w, format: format}
+}
+
+func (r *recorder) write(f *frame, offset time.Duration) error {
+	ms := offset.Milliseconds()
+	r.buf = r.buf[:0]
+
+	switch r.format {
+	case RecordSSE:
+		r.buf = append(r.buf, ": t="...)
+		r.buf = strconv.AppendInt(r.buf, ms, 10)
+		r.buf = append(r.buf, '\n')
+		if f.id != nil {
+			r.buf = append(r.buf, "id: "...)
+			r.buf = append(r.buf, *f.id...)
+			r.buf = append(r.buf, '\n')
+		}
+		if f.event != nil {
+			r.buf = append(r.buf, "event: "...)
+			r.buf = append(r.buf, *f.event...)
+			r.buf = append(r.buf, '\n')
+		}
+		if f.retry != nil {
+			r.buf = append(r.buf, "retry: "...)
+			r.buf = strconv.AppendInt(r.buf, *f.retry, 10)
+			r.buf = append(r.buf, '\n')
+		}
+		if len(f.data) > 0 {
+			for _, line := range bytes.Split(f.data, []byte("\n")) {
+				r.buf = append(r.buf, "data: "...)
+				r.buf = append(r.buf, line...)
+				r.buf = append(r.buf, '\n')
+			}
+		}
+		r.buf = append(r.buf, '\n')
+	default:
+		line, err := json.Marshal(RecordedFrame{
+			Offset: ms,
+			ID:     f.id,
+			Event:  f.event,
+			Data:   string(f.data),
+			Retry:  f.retry,
+		})
+		if err != nil {
+			return err
+		}
+		r.buf = append(append(r.buf, line...), '\n')
+	}
+
+	if _, err := r.w.Write(r.buf); err != nil {
+		return fmt.Errorf("stream: recording event: %w", err)
+	}
+	return nil
+}
+
+type replayOptions struct {
+	speed  float64
+	stream []Option
+}
+
+// ReplayOption configures Replay.
+type ReplayOption func(*replayOptions)
+
+// WithSpeed replays events at the given multiple of their recorded pace: 1
+// reproduces the original timing and 10 plays ten times faster. Zero, the
+// default, delivers events as fast as they are read.
+func WithSpeed(factor float64) ReplayOption {
+	return func(o *replayOptions) {
+		o.speed = factor
+	}
+}
+
+// WithReplayStreamOptions passes options through to the replayed EventStream.
+func WithReplayStreamOptions(opts ...Option) ReplayOption {
+	return func(o *replayOptions) {
+		o.stream = append(o.stream, opts...)
+	}
+}
+
+// Replay returns an EventStream that yields the events of a recording made by
+// Record, in either format. Plain text/event-stream captures without offsets
+// are also accepted. The stream is decoded exactly as a live one would be.
+func Replay[T any](ctx context.Context, recording io.Reader, unmarshaller func(se []byte) (T, error), opts ...ReplayOption) *EventStream[T] {
+	var o replayOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(replay(ctx, recording, pw, o.speed))
+	}()
+
+	return NewEventStream(ctx, pr, unmarshaller, "", o.stream...)
+}
+
+func replay(ctx context.Context, recording io.Reader, w io.Writer, speed float64) error {
+	br := bufio.NewReader(recording)
+	start := time.Now()
+
+	wait := func(offset int64) error {
+		if speed <= 0 {
+			return nil
+		}
+		d := time.Duration(float64(offset)*float64(time.Millisecond)/speed) - time.Since(start)
+		if d <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+
+	head, _ := br.Peek(1)
+	jsonl := len(head) == 1 && head[0] == '{'
+
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if jsonl {
+				var rf RecordedFrame
+				if err := json.Unmarshal([]byte(line), &rf); err != nil {
+					return fmt.Errorf("stream: invalid recording: %w", err)
+				}
+				if err := wait(rf.Offset); err != nil {
+					return err
+				}
+				if _, err := io.WriteString(w, rf.sse()); err != nil {
+					return err
+				}
+			} else {
+				if ms, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), ": t="); ok {
+					if offset, err := strconv.ParseInt(ms, 10, 64); err == nil {
+						if err := wait(offset); err != nil {
+							return err
+						}
+					}
+				}
+				if _, err := io.WriteString(w, line); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (rf RecordedFrame) sse() string {
+	var b strings.Builder
+	if rf.ID != nil {
+		b.WriteString("id: " + *rf.ID + "\n")
+	}
+	if rf.Event != nil {
+		b.WriteString("event: " + *rf.Event + "\n")
+	}
+	if rf.Retry != nil {
+		b.WriteString("retry: " + strconv.FormatInt(*rf.Retry, 10) + "\n")
+	}
+	if rf.Data != "" {
+		for _, line := range strings.Split(rf.Data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
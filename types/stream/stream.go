@@ -1,16 +1,12 @@
-// Code generated by Speakeasy (https://speakeasy.com). DO NOT EDIT.
-
 package stream
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"io"
-	"regexp"
-	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type ServerEvent struct {
@@ -20,27 +16,20 @@ type ServerEvent struct {
 	Retry *int64          `json:"retry,omitempty"`
 }
 
-var (
-	boundary   = regexp.MustCompile(`\r\n\r\n|\r\n\r|\r\n\n|\r\r\n|\n\r\n|\r\r|\n\r|\n\n`)
-	lineEnding = regexp.MustCompile(`\r\n|\r|\n`)
-	bom        = "\uFEFF"
-)
-
-func scanServerEvents(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF && len(data) == 0 {
-		return 0, nil, nil
-	}
-
-	result := boundary.FindIndex(data)
-	if result != nil {
-		return result[1], data[:result[0]], nil
-	}
+// DecodeError reports an event that was received intact but could not be
+// decoded into the stream's value type. Unlike transport failures, replaying
+// the same event will fail the same way, so it should not trigger a reconnect.
+type DecodeError struct {
+	Event ServerEvent
+	Err   error
+}
 
-	if atEOF {
-		return len(data), bytes.TrimRight(data, "\r\n"), nil
-	}
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
 
-	return 0, nil, nil
+func (e *DecodeError) Unwrap() error {
+	return e.Err
 }
 
 type EventType interface {
@@ -49,16 +38,51 @@ type EventType interface {
 
 type EventStream[T any] struct {
 	r            io.ReadCloser
-	scanner      *bufio.Scanner
+	decoder      *decoder
 	unmarshaller func(se []byte) (T, error)
 	sentinel     string
 	ctx          context.Context
+	eventType    EventType
+	direct       func(id, event *string, retry *int64, data []byte) (T, bool, error)
+	monitor      *monitor
+	recorder     *recorder
+
+	finished  atomic.Bool
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+	val       *T
+	eventID   *string
+	retry     *time.Duration
+}
+
+type options struct {
+	maxEventSize   int
+	idleTimeout    time.Duration
+	heartbeatEvent string
+	decoder        any
+}
+
+// Option configures an EventStream.
+type Option func(*options)
+
+// WithMaxEventSize sets the largest event, in bytes, the stream will buffer
+// before failing with ErrEventTooLarge. A size of zero or less removes the
+// limit.
+func WithMaxEventSize(size int) Option {
+	return func(o *options) {
+		o.maxEventSize = size
+	}
+}
 
-	finished bool
-	first    bool
-	err      error
-	val      *T
-	eventID  *string
+// WithDecoder installs a decoder that builds values directly from an event's
+// fields, skipping the JSON envelope passed to the unmarshaller. It returns
+// false for events it does not handle, which then go through the unmarshaller.
+// data must not be retained. The decoder's type must match the stream's.
+func WithDecoder[T any](decode func(id, event *string, retry *int64, data []byte) (T, bool, error)) Option {
+	return func(o *options) {
+		o.decoder = decode
+	}
 }
 
 func NewEventStream[T any](
@@ -66,9 +90,12 @@ func NewEventStream[T any](
 	source io.Reader,
 	unmarshaller func(se []byte) (T, error),
 	sentinel string,
+	opts ...Option,
 ) *EventStream[T] {
-	scanner := bufio.NewScanner(source)
-	scanner.Split(scanServerEvents)
+	o := options{maxEventSize: DefaultMaxEventSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	var src io.ReadCloser
 	if s, ok := source.(io.ReadCloser); ok {
@@ -81,14 +108,23 @@ func NewEventStream[T any](
 		ctx = context.Background()
 	}
 
-	return &EventStream[T]{
+	var t T
+	et, _ := any(t).(EventType)
+
+	es := &EventStream[T]{
 		r:            src,
-		scanner:      scanner,
+		decoder:      newDecoder(source, o.maxEventSize),
 		unmarshaller: unmarshaller,
 		sentinel:     sentinel,
 		ctx:          ctx,
-		first:        true,
+		eventType:    et,
+		done:         make(chan struct{}),
 	}
+	es.direct, _ = o.decoder.(func(id, event *string, retry *int64, data []byte) (T, bool, error))
+	es.monitor = newMonitor(o, func() { src.Close() })
+	es.decoder.monitor = es.monitor
+
+	return es
 }
 
 // Next waits for the next event from a stream which will be available
@@ -96,7 +132,7 @@ func NewEventStream[T any](
 // an error occurred. After this method returns false, the Err method is used
 // to check for any errors that occurred while parsing the stream.
 func (es *EventStream[T]) Next() bool {
-	if es.err != nil || es.finished {
+	if es.err != nil || es.finished.Load() {
 		return false
 	}
 
@@ -108,127 +144,78 @@ func (es *EventStream[T]) Next() bool {
 	default:
 	}
 
-	if !es.scanner.Scan() {
-		return false
-	}
-
-	es.err = es.scanner.Err()
-	if es.err != nil {
+	f, err := es.decoder.next()
+	if err != nil {
+		if es.monitor.isStalled() {
+			es.err = ErrStalled
+		} else if err != io.EOF {
+			es.err = err
+		}
 		return false
 	}
 
-	b := es.scanner.Bytes()
-	content := string(b)
-	if es.first {
-		es.first = false
-		content = strings.TrimPrefix(content, bom)
-	}
-
-	var event ServerEvent
-	lines := lineEnding.Split(content, -1)
-	publish := false
-	data := ""
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		delim := strings.Index(line, ":")
-		if delim == 0 {
-			continue
-		}
-
-		var field, value string
-		if delim > 0 {
-			field = line[:delim]
-			value = line[delim+1:]
-			value = strings.TrimPrefix(value, " ")
-		} else {
-			field = line
-			value = ""
-		}
+	es.monitor.observe(f)
 
-		switch field {
-		case "id":
-			publish = true
-			if !strings.Contains(value, "\x00") {
-				es.eventID = &value
-			}
-		case "event":
-			publish = true
-			event.Event = &value
-		case "retry":
-			retry, err := strconv.ParseInt(value, 10, 64)
-			if err == nil {
-				publish = true
-				event.Retry = &retry
-			}
-		case "data":
-			publish = true
-			data += value + "\n"
+	if es.recorder != nil && f.publish {
+		if err := es.recorder.write(f, time.Since(es.monitor.openedAt())); err != nil {
+			es.err = err
+			return false
 		}
 	}
 
-	event.ID = es.eventID
+	es.eventID = f.id
+	if f.retry != nil {
+		d := time.Duration(*f.retry) * time.Millisecond
+		es.retry = &d
+	}
 
-	if es.sentinel != "" && data == es.sentinel+"\n" {
-		es.finished = true
+	if es.sentinel != "" && string(f.data) == es.sentinel {
+		es.finished.Store(true)
 		return false
 	}
 
-	if len(data) > 0 {
-		data = data[:len(data)-1]
+	if !f.publish {
+		es.val = nil
+		return true
 	}
 
-	encoding := "application/json"
-
-	var t T
-	if et, ok := any(t).(EventType); ok {
-		ev := ""
-		if event.Event != nil {
-			ev = *event.Event
-		}
-		var err error
-		encoding, err = et.GetEventEncoding(ev)
+	if es.direct != nil {
+		v, ok, err := es.direct(f.id, f.event, f.retry, f.data)
 		if err != nil {
-			es.err = err
+			es.err = &DecodeError{Event: f.serverEvent(false), Err: err}
 			return false
 		}
-	} else {
-		var a interface{}
-		if err := json.Unmarshal([]byte(data), &a); err != nil {
-			encoding = "string"
+		if ok {
+			es.val = &v
+			return true
 		}
 	}
 
-	if encoding == "string" {
-		jsonData, err := json.Marshal(data)
+	quoteData := false
+	if es.eventType != nil {
+		ev := ""
+		if f.event != nil {
+			ev = *f.event
+		}
+		encoding, err := es.eventType.GetEventEncoding(ev)
 		if err != nil {
-			es.err = err
+			es.err = &DecodeError{Event: f.serverEvent(false), Err: err}
 			return false
 		}
-		event.Data = jsonData
+		// Objects are embedded as-is; anything else is checked so that text
+		// sent on an event the type does not know still decodes.
+		quoteData = encoding == "string" || (len(f.data) > 0 && f.data[0] != '{' && !json.Valid(f.data))
 	} else {
-		event.Data = []byte(data)
+		quoteData = !json.Valid(f.data)
 	}
 
-	e, err := json.Marshal(event)
+	parsedEvent, err := es.unmarshaller(appendEnvelope(nil, f, quoteData))
 	if err != nil {
-		es.err = err
+		es.err = &DecodeError{Event: f.serverEvent(quoteData), Err: err}
 		return false
 	}
 
-	parsedEvent, err := es.unmarshaller(e)
-	if err != nil {
-		es.err = err
-		return false
-	}
-
-	if publish {
-		es.val = &parsedEvent
-	} else {
-		es.val = nil
-	}
+	es.val = &parsedEvent
 
 	return true
 }
@@ -238,6 +225,25 @@ func (es *EventStream[T]) Value() *T {
 	return es.val
 }
 
+// LastEventID returns the most recent event ID received on the stream, or nil
+// if the server has not sent one. It is suitable for resuming the stream via
+// the Last-Event-ID header.
+func (es *EventStream[T]) LastEventID() *string {
+	return es.eventID
+}
+
+// RetryInterval returns the reconnection delay most recently advertised by the
+// server through the retry field, or nil if it has not sent one.
+func (es *EventStream[T]) RetryInterval() *time.Duration {
+	return es.retry
+}
+
+// Stats returns liveness statistics for the stream. It is safe to call from
+// any goroutine.
+func (es *EventStream[T]) Stats() Stats {
+	return es.monitor.snapshot()
+}
+
 // Err returns the first non-EOF error that was encountered
 func (es *EventStream[T]) Err() error {
 	return es.err
@@ -246,6 +252,8 @@ func (es *EventStream[T]) Err() error {
 // Close will release underlying resources held by an event stream. It must
 // always be called.
 func (es *EventStream[T]) Close() error {
-	es.finished = true
+	es.finished.Store(true)
+	es.closeOnce.Do(func() { close(es.done) })
+	es.monitor.stop()
 	return es.r.Close()
 }
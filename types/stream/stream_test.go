@@ -0,0 +1,384 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	ID    *string         `json:"id"`
+	Event *string         `json:"event"`
+	Data  json.RawMessage `json:"data"`
+	Retry *int64          `json:"retry"`
+}
+
+func unmarshalTestEvent(se []byte) (testEvent, error) {
+	var e testEvent
+	err := json.Unmarshal(se, &e)
+	return e, err
+}
+
+func collect(t *testing.T, body string, opts ...Option) ([]testEvent, error) {
+	t.Helper()
+	es := NewEventStream(context.Background(), strings.NewReader(body), unmarshalTestEvent, "", opts...)
+	defer es.Close()
+
+	var events []testEvent
+	for es.Next() {
+		if v := es.Value(); v != nil {
+			events = append(events, *v)
+		}
+	}
+	return events, es.Err()
+}
+
+func TestEventStream_LineEndings(t *testing.T) {
+	t.Parallel()
+	for name, sep := range map[string]string{"LF": "\n", "CR": "\r", "CRLF": "\r\n"} {
+		sep := sep
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			body := "event: a" + sep + "data: {\"n\":1}" + sep + sep + "event: b" + sep + "data: {\"n\":2}" + sep + sep
+			events, err := collect(t, body)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			assert.Equal(t, "a", *events[0].Event)
+			assert.JSONEq(t, `{"n":1}`, string(events[0].Data))
+			assert.Equal(t, "b", *events[1].Event)
+		})
+	}
+}
+
+func TestEventStream_FieldParsing(t *testing.T) {
+	t.Parallel()
+	body := "\uFEFF: comment\nid: 7\nevent: msg\nretry: 1500\ndata: hello\ndata:world\n\ndata: {\"x\":true}\n\n"
+	events, err := collect(t, body)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "7", *events[0].ID)
+	assert.Equal(t, "msg", *events[0].Event)
+	assert.Equal(t, int64(1500), *events[0].Retry)
+	assert.Equal(t, `"hello\nworld"`, string(events[0].Data))
+
+	// The last event ID carries over to events that do not set one.
+	assert.Equal(t, "7", *events[1].ID)
+	assert.JSONEq(t, `{"x":true}`, string(events[1].Data))
+}
+
+func TestEventStream_DispatchesTrailingEventAtEOF(t *testing.T) {
+	t.Parallel()
+	events, err := collect(t, "event: last\ndata: {}")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "last", *events[0].Event)
+}
+
+func TestEventStream_LargeEvents(t *testing.T) {
+	t.Parallel()
+	payload := strings.Repeat("x", 1<<20)
+	body := fmt.Sprintf("event: big\ndata: {\"content\":%q}\n\n", payload)
+
+	events, err := collect(t, body)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Len(t, events[0].Data, len(payload)+len(`{"content":""}`))
+
+	_, err = collect(t, body, WithMaxEventSize(64<<10))
+	assert.ErrorIs(t, err, ErrEventTooLarge)
+
+	events, err = collect(t, body, WithMaxEventSize(0))
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestEventStream_Sentinel(t *testing.T) {
+	t.Parallel()
+	body := "data: {}\n\ndata: [DONE]\n\ndata: {}\n\n"
+	es := NewEventStream(context.Background(), strings.NewReader(body), unmarshalTestEvent, "[DONE]")
+	n := 0
+	for es.Next() {
+		n++
+	}
+	require.NoError(t, es.Err())
+	assert.Equal(t, 1, n)
+}
+
+func TestEventStream_DecodeError(t *testing.T) {
+	t.Parallel()
+	type numericEvent struct {
+		Data int `json:"data"`
+	}
+	unmarshal := func(se []byte) (numericEvent, error) {
+		var v numericEvent
+		err := json.Unmarshal(se, &v)
+		return v, err
+	}
+	es := NewEventStream(context.Background(), strings.NewReader("id: 3\nevent: x\ndata: {}\n\n"), unmarshal, "")
+	require.False(t, es.Next())
+
+	var decodeErr *DecodeError
+	require.ErrorAs(t, es.Err(), &decodeErr)
+	assert.Equal(t, "x", *decodeErr.Event.Event)
+	assert.Equal(t, "3", *decodeErr.Event.ID)
+}
+
+// longTurn builds a stream shaped like a long agent turn: many small content
+// and parameter deltas interleaved with a few large tool results.
+func longTurn(events int) string {
+	var sb strings.Builder
+	large := strings.Repeat("output line\\n", 8<<10)
+	for i := 0; i < events; i++ {
+		fmt.Fprintf(&sb, "id: %d\n", i)
+		switch {
+		case i%500 == 499:
+			fmt.Fprintf(&sb, "event: tool_execution_complete\ndata: {\"toolCallId\":\"t%d\",\"progress\":\"%s\",\"success\":true,\"type\":\"tool_execution_complete\"}\n\n", i, large)
+		case i%3 == 0:
+			fmt.Fprintf(&sb, "event: tool_use_parameter_delta\ndata: {\"toolCallId\":\"t%d\",\"input\":\"{\\\"command\\\": \\\"ls\",\"type\":\"tool_use_parameter_delta\"}\n\n", i)
+		default:
+			sb.WriteString("event: content\ndata: {\"content\":\"Some streamed assistant text \",\"type\":\"content\"}\n\n")
+		}
+	}
+	return sb.String()
+}
+
+func BenchmarkEventStream_LongTurn(b *testing.B) {
+	body := longTurn(5000)
+	unmarshal := func(se []byte) (json.RawMessage, error) {
+		return se, nil
+	}
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		es := NewEventStream(context.Background(), io.NopCloser(strings.NewReader(body)), unmarshal, "")
+		for es.Next() {
+		}
+		if err := es.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventStream_SmallEvents(b *testing.B) {
+	body := strings.Repeat("event: content\ndata: {\"content\":\"tok\",\"type\":\"content\"}\n\n", 10000)
+	unmarshal := func(se []byte) (json.RawMessage, error) {
+		return se, nil
+	}
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		es := NewEventStream(context.Background(), strings.NewReader(body), unmarshal, "")
+		for es.Next() {
+		}
+		if err := es.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEventStream_IdleTimeout(t *testing.T) {
+	t.Parallel()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	es := NewEventStream(context.Background(), pr, unmarshalTestEvent, "", WithIdleTimeout(50*time.Millisecond), WithHeartbeatEvent("heartbeat"))
+	defer es.Close()
+
+	go func() {
+		pw.Write([]byte("event: heartbeat\ndata: {}\n\n: keep-alive\n\n"))
+	}()
+
+	require.True(t, es.Next())
+	require.True(t, es.Next())
+	assert.False(t, es.Next())
+	assert.ErrorIs(t, es.Err(), ErrStalled)
+
+	stats := es.Stats()
+	assert.Equal(t, int64(2), stats.Frames)
+	assert.Equal(t, int64(1), stats.Heartbeats)
+	assert.False(t, stats.LastHeartbeatAt.IsZero())
+	assert.Len(t, stats.Gaps, len(GapBuckets)+1)
+	assert.Equal(t, int64(2), stats.Gaps[0])
+}
+
+func TestEventStream_IdleTimeoutSlowConsumer(t *testing.T) {
+	t.Parallel()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	es := NewEventStream(context.Background(), pr, unmarshalTestEvent, "", WithIdleTimeout(50*time.Millisecond))
+	defer es.Close()
+
+	go func() {
+		pw.Write([]byte("event: message\ndata: {}\n\n"))
+		pw.Write([]byte("event: message\ndata: {}\n\n"))
+	}()
+
+	require.True(t, es.Next())
+	// The consumer taking longer than the timeout does not stall the stream.
+	time.Sleep(150 * time.Millisecond)
+	require.True(t, es.Next(), es.Err())
+	assert.False(t, es.Next())
+	assert.ErrorIs(t, es.Err(), ErrStalled)
+}
+
+func TestEventStream_RetryInterval(t *testing.T) {
+	t.Parallel()
+	es := NewEventStream(context.Background(), strings.NewReader("retry: 1500\ndata: {}\n\ndata: {}\n\n"), unmarshalTestEvent, "")
+	defer es.Close()
+
+	assert.Nil(t, es.RetryInterval())
+	require.True(t, es.Next())
+	require.True(t, es.Next())
+	require.NotNil(t, es.RetryInterval())
+	assert.Equal(t, 1500*time.Millisecond, *es.RetryInterval())
+}
+
+func numberedBody(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "data: %d\n\n", i)
+	}
+	return b.String()
+}
+
+func TestEventStream_EventsOverflow(t *testing.T) {
+	t.Parallel()
+	const total = 50
+
+	t.Run("DropOldest", func(t *testing.T) {
+		t.Parallel()
+		es := NewEventStream(context.Background(), strings.NewReader(numberedBody(total)), unmarshalTestEvent, "")
+		defer es.Close()
+
+		ch := es.Events(WithBuffer[testEvent](4), WithOverflow[testEvent](OverflowDropOldest))
+		require.Eventually(t, func() bool { return es.Stats().Frames == total }, time.Second, time.Millisecond)
+
+		var got []string
+		for e := range ch {
+			got = append(got, string(e.Data))
+		}
+		require.NoError(t, es.Err())
+		assert.Equal(t, fmt.Sprint(total), got[len(got)-1])
+		assert.Equal(t, int64(total), int64(len(got))+es.Stats().Dropped)
+	})
+
+	t.Run("Coalesce", func(t *testing.T) {
+		t.Parallel()
+		es := NewEventStream(context.Background(), strings.NewReader(numberedBody(total)), unmarshalTestEvent, "")
+		defer es.Close()
+
+		sum := func(last, next testEvent) (testEvent, bool) {
+			var a, b int
+			json.Unmarshal(last.Data, &a)
+			json.Unmarshal(next.Data, &b)
+			next.Data = json.RawMessage(fmt.Sprint(a + b))
+			return next, true
+		}
+		ch := es.Events(WithBuffer[testEvent](2), WithCoalesce(sum))
+
+		got := 0
+		for e := range ch {
+			var n int
+			require.NoError(t, json.Unmarshal(e.Data, &n))
+			got += n
+		}
+		require.NoError(t, es.Err())
+		assert.Equal(t, total*(total+1)/2, got)
+		assert.Zero(t, es.Stats().Dropped)
+	})
+}
+
+func TestEventStream_EventsCancel(t *testing.T) {
+	t.Parallel()
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	es := NewEventStream(ctx, pr, unmarshalTestEvent, "")
+	defer es.Close()
+
+	ch := es.Events()
+	go pw.Write([]byte("event: message\ndata: {}\n\n"))
+	<-ch
+
+	// The channel stays open until the read in progress returns, so that Err
+	// is final once it is closed.
+	cancel()
+	select {
+	case <-ch:
+		t.Fatal("channel closed while a read was in progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+	errReset := errors.New("connection reset")
+	pw.CloseWithError(errReset)
+	for range ch {
+	}
+	assert.ErrorIs(t, es.Err(), errReset)
+}
+
+func TestEventStream_RecordReplay(t *testing.T) {
+	t.Parallel()
+	body := "id: 1\nevent: a\ndata: {\"n\":1}\n\n: keep-alive\n\nid: 2\nevent: b\nretry: 100\ndata: line one\ndata: line two\n\n"
+
+	for name, format := range map[string]RecordFormat{"JSONL": RecordJSONL, "SSE": RecordSSE} {
+		format := format
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var recording bytes.Buffer
+			es := NewEventStream(context.Background(), strings.NewReader(body), unmarshalTestEvent, "")
+			es.Record(&recording, format)
+			for es.Next() {
+			}
+			require.NoError(t, es.Err())
+			es.Close()
+
+			live, err := collect(t, body)
+			require.NoError(t, err)
+
+			replayed := Replay(context.Background(), bytes.NewReader(recording.Bytes()), unmarshalTestEvent, WithSpeed(1000))
+			defer replayed.Close()
+			var got []testEvent
+			for replayed.Next() {
+				if v := replayed.Value(); v != nil {
+					got = append(got, *v)
+				}
+			}
+			require.NoError(t, replayed.Err())
+			assert.Equal(t, live, got)
+		})
+	}
+}
+
+func TestEventStream_RecordSSE_NoData(t *testing.T) {
+	t.Parallel()
+	body := "id: 1\nevent: ping\n\nid: 2\nevent: a\ndata: {\"n\":1}\n\n"
+
+	var recording bytes.Buffer
+	es := NewEventStream(context.Background(), strings.NewReader(body), unmarshalTestEvent, "")
+	es.Record(&recording, RecordSSE)
+	for es.Next() {
+	}
+	require.NoError(t, es.Err())
+	es.Close()
+
+	var frames []string
+	for _, line := range strings.SplitAfter(recording.String(), "\n") {
+		if !strings.HasPrefix(line, ": t=") {
+			frames = append(frames, line)
+		}
+	}
+	assert.Equal(t, body, strings.Join(frames, ""))
+}
@@ -0,0 +1,178 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStalled is returned when no event, heartbeat or keep-alive comment
+// arrives within the idle timeout configured with WithIdleTimeout. It usually
+// means the connection is half-open and should be re-established.
+var ErrStalled = errors.New("stream: no activity within idle timeout")
+
+// GapBuckets are the upper bounds of the gap histogram reported in Stats. The
+// last bucket collects every gap longer than the largest bound.
+var GapBuckets = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Stats describes the liveness of a stream connection.
+type Stats struct {
+	// When the stream was opened
+	OpenedAt time.Time
+	// Frames received, including comments and heartbeats
+	Frames int64
+	// Heartbeat events received
+	Heartbeats int64
+	// When the last frame was received; zero if none
+	LastFrameAt time.Time
+	// When the last heartbeat event was received; zero if none
+	LastHeartbeatAt time.Time
+	// Events discarded by the OverflowDropOldest policy of Events
+	Dropped int64
+	// Longest gap observed between consecutive frames
+	MaxGap time.Duration
+	// Gap counts per bucket: Gaps[i] counts gaps up to GapBuckets[i], and the
+	// final entry counts gaps longer than the last bound.
+	Gaps []int64
+}
+
+// WithIdleTimeout enables a liveness watchdog. If Next waits the given
+// duration for data from the underlying reader, the reader is closed and Next
+// returns false with ErrStalled. Time the consumer spends between calls to
+// Next does not count, so a slow consumer does not trip the watchdog. A
+// duration of zero or less disables the watchdog.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithHeartbeatEvent names the event type counted as a heartbeat in Stats.
+func WithHeartbeatEvent(event string) Option {
+	return func(o *options) {
+		o.heartbeatEvent = event
+	}
+}
+
+type monitor struct {
+	heartbeatEvent string
+	idleTimeout    time.Duration
+	timer          *time.Timer
+
+	mu      sync.Mutex
+	stats   Stats
+	stalled bool
+	stopped bool
+}
+
+func newMonitor(o options, onStall func()) *monitor {
+	m := &monitor{
+		heartbeatEvent: o.heartbeatEvent,
+		idleTimeout:    o.idleTimeout,
+		stats: Stats{
+			OpenedAt: time.Now(),
+			Gaps:     make([]int64, len(GapBuckets)+1),
+		},
+	}
+	if m.idleTimeout > 0 {
+		m.timer = time.AfterFunc(m.idleTimeout, func() {
+			m.mu.Lock()
+			m.stalled = true
+			m.mu.Unlock()
+			onStall()
+		})
+		m.timer.Stop()
+	}
+	return m
+}
+
+// reading arms the idle timer before a read from the connection.
+func (m *monitor) reading() {
+	if m.timer == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.stopped {
+		m.timer.Reset(m.idleTimeout)
+	}
+}
+
+// read disarms the idle timer once a read returned, with data or not.
+func (m *monitor) read() {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
+
+func (m *monitor) observe(f *frame) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stalled {
+		return
+	}
+
+	prev := m.stats.LastFrameAt
+	if prev.IsZero() {
+		prev = m.stats.OpenedAt
+	}
+	gap := now.Sub(prev)
+	if gap > m.stats.MaxGap {
+		m.stats.MaxGap = gap
+	}
+	i := 0
+	for i < len(GapBuckets) && gap > GapBuckets[i] {
+		i++
+	}
+	m.stats.Gaps[i]++
+
+	m.stats.Frames++
+	m.stats.LastFrameAt = now
+	if m.heartbeatEvent != "" && f.event != nil && *f.event == m.heartbeatEvent {
+		m.stats.Heartbeats++
+		m.stats.LastHeartbeatAt = now
+	}
+}
+
+func (m *monitor) drop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Dropped++
+}
+
+func (m *monitor) openedAt() time.Time {
+	return m.stats.OpenedAt
+}
+
+func (m *monitor) isStalled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stalled
+}
+
+func (m *monitor) snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats
+	s.Gaps = append([]int64(nil), m.stats.Gaps...)
+	return s
+}
+
+func (m *monitor) stop() {
+	if m.timer == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	m.timer.Stop()
+}